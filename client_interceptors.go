@@ -2,27 +2,40 @@ package grpc_slog
 
 import (
 	"context"
+	"io"
 	"path"
+	"sync"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	"cdr.dev/slog"
+	"github.com/hassieswift621/slog-grpc-mw/ctxslog"
 )
 
 var (
 	// ClientField is used in every client-side log statement made through grpc_slog. Can be overwritten before initialization.
-	ClientField = slog.Field{"span.kind", "client"}
+	ClientField = slog.Field{Name: "span.kind", Value: "client"}
 )
 
 // UnaryClientInterceptor returns a new unary client interceptor that optionally logs the execution of external gRPC calls.
 func UnaryClientInterceptor(logger slog.Logger, opts ...Option) grpc.UnaryClientInterceptor {
 	o := evaluateClientOpt(opts)
 	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
-		fields := newClientLoggerFields(ctx, method)
+		o := o.forMethod(method)
+		ctx, requestID := outgoingRequestID(ctx, o)
+		fields := clientCallFields(ctx, o, method, requestID)
+		callLogger := clientLogger(ctx, o, logger, fields)
 		startTime := time.Now()
+		logStartClientLine(ctx, o, logger, fields, method, "started client unary call")
 		err := invoker(ctx, method, req, reply, cc, opts...)
-		logFinalClientLine(ctx, o, logger.With(fields...), startTime, err, "finished client unary call")
+		var statsFields []slog.Field
+		if o.messageStats {
+			statsFields = unaryStatsFields(req, reply, err)
+		}
+		logFinalClientLine(ctx, o, callLogger, method, startTime, err, "finished client unary call", statsFields...)
 		return err
 	}
 }
@@ -31,22 +44,158 @@ func UnaryClientInterceptor(logger slog.Logger, opts ...Option) grpc.UnaryClient
 func StreamClientInterceptor(logger slog.Logger, opts ...Option) grpc.StreamClientInterceptor {
 	o := evaluateClientOpt(opts)
 	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
-		fields := newClientLoggerFields(ctx, method)
+		o := o.forMethod(method)
+		ctx, requestID := outgoingRequestID(ctx, o)
+		fields := clientCallFields(ctx, o, method, requestID)
+		callLogger := clientLogger(ctx, o, logger, fields)
 		startTime := time.Now()
+		logStartClientLine(ctx, o, logger, fields, method, "started client streaming call")
 		clientStream, err := streamer(ctx, desc, cc, method, opts...)
-		logFinalClientLine(ctx, o, logger.With(fields...), startTime, err, "finished client streaming call")
-		return clientStream, err
+		if err != nil {
+			logFinalClientLine(ctx, o, callLogger, method, startTime, err, "finished client streaming call")
+			return clientStream, err
+		}
+		var stats *streamStats
+		if o.messageStats {
+			stats = &streamStats{}
+		}
+		return newMonitoredClientStream(ctx, clientStream, desc, stats, func(err error) {
+			var statsFields []slog.Field
+			if stats != nil {
+				statsFields = stats.fields()
+			}
+			logFinalClientLine(ctx, o, callLogger, method, startTime, err, "finished client streaming call", statsFields...)
+		}), nil
 	}
 }
 
-func logFinalClientLine(ctx context.Context, o *options, logger slog.Logger, startTime time.Time, err error, msg string) {
+// monitoredClientStream wraps a grpc.ClientStream and calls onFinish exactly once when the stream ends.
+//
+// A stream is considered finished when RecvMsg returns io.EOF or any other error, when the single response of a
+// non server-streaming call has been received, or when the context of the call is done.
+// Messages are recorded in stats when it is not nil.
+type monitoredClientStream struct {
+	grpc.ClientStream
+	desc     *grpc.StreamDesc
+	stats    *streamStats
+	onFinish func(err error)
+	once     sync.Once
+	done     chan struct{}
+}
+
+func newMonitoredClientStream(ctx context.Context, stream grpc.ClientStream, desc *grpc.StreamDesc, stats *streamStats, onFinish func(err error)) *monitoredClientStream {
+	s := &monitoredClientStream{
+		ClientStream: stream,
+		desc:         desc,
+		stats:        stats,
+		onFinish:     onFinish,
+		done:         make(chan struct{}),
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.finish(status.FromContextError(ctx.Err()).Err())
+		case <-s.done:
+		}
+	}()
+	return s
+}
+
+func (s *monitoredClientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil && s.stats != nil {
+		s.stats.sent(m)
+	}
+	// io.EOF on SendMsg means the stream was terminated by the server, the real status is returned by RecvMsg.
+	if err != nil && err != io.EOF {
+		s.finish(err)
+	}
+	return err
+}
+
+func (s *monitoredClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == nil && s.stats != nil {
+		s.stats.received(m)
+	}
+	switch {
+	case err == io.EOF:
+		s.finish(nil)
+	case err != nil:
+		s.finish(err)
+	case !s.desc.ServerStreams:
+		// Calls without server streaming receive exactly one response.
+		s.finish(nil)
+	}
+	return err
+}
+
+func (s *monitoredClientStream) finish(err error) {
+	s.once.Do(func() {
+		close(s.done)
+		s.onFinish(err)
+	})
+}
+
+// logStartClientLine logs the start of a call with the call fields. The deadline is added to the fields of the logger
+// rather than of the entry, so it replaces the deadline of the inbound call when logging through the context logger.
+func logStartClientLine(ctx context.Context, o *options, logger slog.Logger, fields []slog.Field, fullMethodString string, msg string) {
+	if !o.logStart || !o.shouldLog(fullMethodString, nil) {
+		return
+	}
+	if d, ok := ctx.Deadline(); ok {
+		fields = append(fields[:len(fields):len(fields)], slog.F("grpc.request.deadline", d.Format(time.RFC3339)))
+	}
+	log(ctx, clientLogger(ctx, o, logger, fields), o.startLevel, msg)
+}
+
+func logFinalClientLine(ctx context.Context, o *options, logger slog.Logger, fullMethodString string, startTime time.Time, err error, msg string, extraFields ...slog.Field) {
+	if !o.shouldLog(fullMethodString, err) {
+		return
+	}
+	duration := time.Now().Sub(startTime)
 	code := o.codeFunc(err)
-	level := o.levelFunc(code)
-	log(ctx, logger, level, msg,
+	level, levelFields := o.callLevel(code, duration)
+	sampled, samplingFields := sampleCall(o, fullMethodString, code, duration)
+	if !sampled {
+		return
+	}
+	fields := append([]slog.Field{
 		slog.Error(err),
 		slog.F("grpc.code", code.String()),
-		o.durationFunc(time.Now().Sub(startTime)),
-	)
+		o.durationFunc(duration),
+	}, extraFields...)
+	fields = append(fields, levelFields...)
+	if o.statusDetails && err != nil {
+		fields = append(fields, statusDetailsFields(err)...)
+	}
+	log(ctx, logger, level, msg, append(fields, samplingFields...)...)
+}
+
+// clientLogger returns the logger of a client call with the given fields, which is the call-scoped logger of the
+// context if enabled and present.
+func clientLogger(ctx context.Context, o *options, logger slog.Logger, fields []slog.Field) slog.Logger {
+	if o.useContextLogger && ctxslog.Has(ctx) {
+		return ctxslog.ExtractWith(ctx, fields...)
+	}
+	return logger.With(fields...)
+}
+
+// clientCallFields returns the fields of the client call logger, including the ones enabled by the options.
+func clientCallFields(ctx context.Context, o *options, fullMethodString string, requestID string) []slog.Field {
+	fields := newClientLoggerFields(ctx, fullMethodString)
+	if requestID != "" {
+		fields = append(fields, slog.F("request_id", requestID))
+	}
+	// Reading the outgoing metadata allocates, so it is only done when it is logged.
+	if o.metadata.enabled || o.traceContext {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		fields = append(fields, metadataFields(md, &o.metadata)...)
+		if o.traceContext {
+			fields = append(fields, traceContextFields(md)...)
+		}
+	}
+	return append(fields, o.fields...)
 }
 
 func newClientLoggerFields(ctx context.Context, fullMethodString string) []slog.Field {
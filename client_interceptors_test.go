@@ -1,19 +1,29 @@
 package grpc_slog_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"io"
+	"io/ioutil"
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"cdr.dev/slog"
+	"cdr.dev/slog/sloggers/slogjson"
+	"github.com/golang/protobuf/proto"
+	grpc_testing "github.com/grpc-ecosystem/go-grpc-middleware/testing"
 	pb_testproto "github.com/grpc-ecosystem/go-grpc-middleware/testing/testproto"
 	grpc_slog "github.com/hassieswift621/slog-grpc-mw"
+	"github.com/hassieswift621/slog-grpc-mw/ctxslog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 )
 
 func customClientCodeToLevel(c codes.Code) slog.Level {
@@ -88,6 +98,67 @@ func (s *slogClientSuite) TestPingList() {
 	assert.Contains(s.T(), f, "grpc.time_ms", "handler's message must contain time in ms")
 }
 
+func (s *slogClientSuite) TestPingList_LogsOnlyWhenStreamFinishes() {
+	stream, err := s.Client.PingList(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "should not fail on establishing the stream")
+	_, err = stream.Recv()
+	require.NoError(s.T(), err, "reading stream should not fail")
+	assert.Empty(s.T(), s.getOutputJSONs(), "nothing should be logged before the stream is finished")
+
+	for {
+		_, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(s.T(), err, "reading stream should not fail")
+	}
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 1, "one log statement should be logged")
+
+	// Get slog fields.
+	f := msgs[0]["fields"].(map[string]interface{})
+
+	assert.Equal(s.T(), msgs[0]["msg"], "finished client streaming call", "handler's message must contain user message")
+	assert.Equal(s.T(), f["grpc.code"], "OK", "the final line must contain the status of the finished stream")
+}
+
+func (s *slogClientSuite) TestPingList_WithError() {
+	stream, err := s.Client.PingList(s.SimpleCtx(), &pb_testproto.PingRequest{Value: "something", ErrorCodeReturned: uint32(codes.Internal)})
+	require.NoError(s.T(), err, "should not fail on establishing the stream")
+	_, err = stream.Recv()
+	require.Error(s.T(), err, "reading the stream must return the error")
+
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 1, "one log statement should be logged")
+
+	// Get slog fields.
+	f := msgs[0]["fields"].(map[string]interface{})
+
+	assert.Equal(s.T(), f["grpc.code"], codes.Internal.String(), "the final line must contain the status returned by the server")
+	assert.Equal(s.T(), msgs[0]["level"], "WARN", "Internal must remap to LevelWarn in DefaultClientCodeToLevel")
+}
+
+func (s *slogClientSuite) TestPingList_WithCancelledContext() {
+	ctx, cancel := context.WithCancel(s.SimpleCtx())
+	stream, err := s.Client.PingList(ctx, goodPing)
+	require.NoError(s.T(), err, "should not fail on establishing the stream")
+	_, err = stream.Recv()
+	require.NoError(s.T(), err, "reading stream should not fail")
+	cancel()
+
+	var msgs []map[string]interface{}
+	require.Eventually(s.T(), func() bool {
+		msgs = append(msgs, s.getOutputJSONs()...)
+		return len(msgs) > 0
+	}, time.Second, 10*time.Millisecond, "the final line must be logged once the context is cancelled")
+	require.Len(s.T(), msgs, 1, "one log statement should be logged")
+
+	// Get slog fields.
+	f := msgs[0]["fields"].(map[string]interface{})
+
+	assert.Equal(s.T(), f["grpc.code"], codes.Canceled.String(), "the final line must contain the Canceled code")
+}
+
 func (s *slogClientSuite) TestPingError_WithCustomLevels() {
 	for _, tcase := range []struct {
 		code  codes.Code
@@ -198,3 +269,346 @@ func (s *slogClientOverrideSuite) TestPingList_HasOverrides() {
 	assert.NotContains(s.T(), f, "grpc.time_ms", "handler's message must not contain default duration")
 	assert.Contains(s.T(), f, "grpc.duration", "handler's message must contain overridden duration")
 }
+
+func TestSlogClientStartLogSuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skip("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+	opts := []grpc_slog.Option{
+		grpc_slog.WithStartLog(slog.LevelInfo),
+	}
+	b := newBaseSlogSuite(t)
+	b.log = b.log.Leveled(slog.LevelDebug)
+	b.InterceptorTestSuite.ClientOpts = []grpc.DialOption{
+		grpc.WithUnaryInterceptor(grpc_slog.UnaryClientInterceptor(b.log, opts...)),
+		grpc.WithStreamInterceptor(grpc_slog.StreamClientInterceptor(b.log, opts...)),
+	}
+	suite.Run(t, &slogClientStartLogSuite{b})
+}
+
+type slogClientStartLogSuite struct {
+	*slogBaseSuite
+}
+
+func (s *slogClientStartLogSuite) TestPing_HasStartLine() {
+	deadline := time.Now().Add(3 * time.Second)
+	_, err := s.Client.Ping(s.DeadlineCtx(deadline), goodPing)
+	require.NoError(s.T(), err, "there must be not be an error on a successful call")
+
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 2, "two log statements should be logged")
+
+	// Get slog fields.
+	f := msgs[0]["fields"].(map[string]interface{})
+
+	assert.Equal(s.T(), msgs[0]["msg"], "started client unary call", "first line must be the start line")
+	assert.Equal(s.T(), msgs[0]["level"], "INFO", "start line must be logged at the configured level")
+	assert.Equal(s.T(), f["span.kind"], "client", "start line must contain the kind of call (client)")
+	assert.Equal(s.T(), f["grpc.method"], "Ping", "start line must contain method name")
+	assert.Equal(s.T(), f["grpc.request.deadline"], deadline.Format(time.RFC3339), "start line must contain the deadline")
+	assert.Equal(s.T(), msgs[1]["msg"], "finished client unary call", "last line must be the final line")
+}
+
+func TestSlogClientDeciderSuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skip("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+	opts := []grpc_slog.Option{
+		grpc_slog.WithStartLog(slog.LevelInfo),
+		grpc_slog.WithDecider(func(method string, err error) bool {
+			return method != "/mwitkow.testproto.TestService/Ping"
+		}),
+	}
+	b := newBaseSlogSuite(t)
+	b.log = b.log.Leveled(slog.LevelDebug)
+	b.InterceptorTestSuite.ClientOpts = []grpc.DialOption{
+		grpc.WithUnaryInterceptor(grpc_slog.UnaryClientInterceptor(b.log, opts...)),
+		grpc.WithStreamInterceptor(grpc_slog.StreamClientInterceptor(b.log, opts...)),
+	}
+	suite.Run(t, &slogClientDeciderSuite{b})
+}
+
+type slogClientDeciderSuite struct {
+	*slogBaseSuite
+}
+
+func (s *slogClientDeciderSuite) TestPing_IsNotLogged() {
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "there must be not be an error on a successful call")
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 0, "neither the start nor the final line must be logged")
+}
+
+func (s *slogClientDeciderSuite) TestPingEmpty_IsLogged() {
+	_, err := s.Client.PingEmpty(s.SimpleCtx(), &pb_testproto.Empty{})
+	require.NoError(s.T(), err, "there must be not be an error on a successful call")
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 2, "both the start and the final line must be logged")
+}
+
+func TestSlogClientMetadataSuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skip("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+	opts := []grpc_slog.Option{
+		grpc_slog.WithMetadataDenylist("x-secret"),
+	}
+	b := newBaseSlogSuite(t)
+	b.log = b.log.Leveled(slog.LevelDebug)
+	b.InterceptorTestSuite.ClientOpts = []grpc.DialOption{
+		grpc.WithUnaryInterceptor(grpc_slog.UnaryClientInterceptor(b.log, opts...)),
+		grpc.WithStreamInterceptor(grpc_slog.StreamClientInterceptor(b.log, opts...)),
+	}
+	suite.Run(t, &slogClientMetadataSuite{b})
+}
+
+type slogClientMetadataSuite struct {
+	*slogBaseSuite
+}
+
+func (s *slogClientMetadataSuite) TestPing_HasOutgoingMetadata() {
+	ctx := metadata.AppendToOutgoingContext(s.SimpleCtx(),
+		"x-request-id", "abc",
+		"cookie", "session=secret",
+		"x-secret", "secret",
+	)
+	_, err := s.Client.Ping(ctx, goodPing)
+	require.NoError(s.T(), err, "there must be not be an error on a successful call")
+
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 1, "one log statement should be logged")
+
+	// Get slog fields.
+	f := msgs[0]["fields"].(map[string]interface{})
+
+	assert.Equal(s.T(), f["grpc.request.metadata.x-request-id"], "abc", "outgoing metadata must be logged")
+	assert.Equal(s.T(), f["grpc.request.metadata.cookie"], "[redacted]", "cookie must be redacted")
+	assert.NotContains(s.T(), f, "grpc.request.metadata.x-secret", "metadata in the denylist must not be logged")
+}
+
+func TestSlogClientMessageStatsSuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skip("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+	opts := []grpc_slog.Option{
+		grpc_slog.WithMessageStats(),
+	}
+	b := newBaseSlogSuite(t)
+	b.log = b.log.Leveled(slog.LevelDebug)
+	b.InterceptorTestSuite.ClientOpts = []grpc.DialOption{
+		grpc.WithUnaryInterceptor(grpc_slog.UnaryClientInterceptor(b.log, opts...)),
+		grpc.WithStreamInterceptor(grpc_slog.StreamClientInterceptor(b.log, opts...)),
+	}
+	suite.Run(t, &slogClientMessageStatsSuite{b})
+}
+
+type slogClientMessageStatsSuite struct {
+	*slogBaseSuite
+}
+
+func (s *slogClientMessageStatsSuite) TestPing_HasMessageSizes() {
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "there must be not be an error on a successful call")
+
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 1, "one log statement should be logged")
+
+	// Get slog fields.
+	f := msgs[0]["fields"].(map[string]interface{})
+
+	assert.EqualValues(s.T(), proto.Size(goodPing), f["grpc.request.size_bytes"], "final line must contain the request size")
+	assert.Contains(s.T(), f, "grpc.response.size_bytes", "final line must contain the response size")
+}
+
+func (s *slogClientMessageStatsSuite) TestPingList_HasMessageCounts() {
+	stream, err := s.Client.PingList(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "should not fail on establishing the stream")
+	for {
+		_, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(s.T(), err, "reading stream should not fail")
+	}
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 1, "one log statement should be logged")
+
+	// Get slog fields.
+	f := msgs[0]["fields"].(map[string]interface{})
+
+	assert.EqualValues(s.T(), 1, f["grpc.stream.msgs_sent"], "final line must contain the number of messages sent")
+	assert.EqualValues(s.T(), grpc_testing.ListResponseCount, f["grpc.stream.msgs_received"], "final line must contain the number of messages received")
+	assert.EqualValues(s.T(), proto.Size(goodPing), f["grpc.stream.bytes_sent"], "final line must contain the bytes sent")
+}
+
+func TestSlogClientContextLoggerSuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skip("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+	opts := []grpc_slog.Option{
+		grpc_slog.WithContextLogger(),
+		grpc_slog.WithStartLog(slog.LevelInfo),
+	}
+	b := newBaseSlogSuite(t)
+	b.log = b.log.Leveled(slog.LevelDebug)
+	b.InterceptorTestSuite.ClientOpts = []grpc.DialOption{
+		grpc.WithUnaryInterceptor(grpc_slog.UnaryClientInterceptor(slogjson.Make(ioutil.Discard), opts...)),
+		grpc.WithStreamInterceptor(grpc_slog.StreamClientInterceptor(slogjson.Make(ioutil.Discard), opts...)),
+	}
+	suite.Run(t, &slogClientContextLoggerSuite{b})
+}
+
+type slogClientContextLoggerSuite struct {
+	*slogBaseSuite
+}
+
+// duplicateFieldNames returns the names that appear more than once in the fields of a raw JSON log line.
+func duplicateFieldNames(t *testing.T, line []byte) []string {
+	var entry struct {
+		Fields json.RawMessage `json:"fields"`
+	}
+	require.NoError(t, json.Unmarshal(line, &entry), "log output must be valid JSON")
+	dec := json.NewDecoder(bytes.NewReader(entry.Fields))
+	_, err := dec.Token()
+	require.NoError(t, err, "fields must be a JSON object")
+	seen := map[string]bool{}
+	var dups []string
+	for dec.More() {
+		tok, err := dec.Token()
+		require.NoError(t, err, "fields must be a JSON object")
+		name := tok.(string)
+		if seen[name] {
+			dups = append(dups, name)
+		}
+		seen[name] = true
+		var value json.RawMessage
+		require.NoError(t, dec.Decode(&value), "fields must be a JSON object")
+	}
+	return dups
+}
+
+func (s *slogClientContextLoggerSuite) TestPing_UsesContextLogger() {
+	ctx, cancel := context.WithTimeout(s.SimpleCtx(), time.Minute)
+	defer cancel()
+	ctx = ctxslog.ToContext(ctx, s.log.With(slog.F("request_id", "abc")))
+	ctxslog.AddFields(ctx, slog.F("custom_field", "custom_value"))
+	// Fields of the inbound call handled by the caller.
+	ctxslog.AddFields(ctx, grpc_slog.SystemField, grpc_slog.ServerField,
+		slog.F("grpc.service", "mwitkow.testproto.TestService"), slog.F("grpc.method", "PingList"),
+		slog.F("grpc.request.deadline", "inbound"))
+	_, err := s.Client.Ping(ctx, goodPing)
+	require.NoError(s.T(), err, "there must be not be an error on a successful call")
+
+	s.mutexBuffer.Lock()
+	raw := append([]byte(nil), s.buffer.Bytes()...)
+	s.mutexBuffer.Unlock()
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 2, "two log statements should be logged through the context logger")
+
+	assert.Equal(s.T(), msgs[0]["msg"], "started client unary call", "must contain correct message")
+	f := msgs[0]["fields"].(map[string]interface{})
+	assert.Contains(s.T(), f, "grpc.request.deadline", "the start line must contain the deadline of the call")
+	assert.NotEqual(s.T(), f["grpc.request.deadline"], "inbound", "the deadline of the call must replace the inbound one")
+	assert.Equal(s.T(), f["grpc.parent.grpc.request.deadline"], "inbound", "replaced fields of the inbound call must be prefixed")
+
+	// Get slog fields.
+	f = msgs[1]["fields"].(map[string]interface{})
+
+	assert.Equal(s.T(), msgs[1]["msg"], "finished client unary call", "must contain correct message")
+	assert.Equal(s.T(), f["request_id"], "abc", "the fields of the context logger must be present")
+	assert.Equal(s.T(), f["custom_field"], "custom_value", "the fields added to the context must be present")
+	assert.Equal(s.T(), f["span.kind"], "client", "the client fields must be present")
+	assert.Equal(s.T(), f["grpc.method"], "Ping", "the client fields must be present")
+	assert.Equal(s.T(), f["grpc.parent.span.kind"], "server", "replaced fields of the inbound call must be prefixed")
+	assert.Equal(s.T(), f["grpc.parent.grpc.method"], "PingList", "replaced fields of the inbound call must be prefixed")
+	assert.NotContains(s.T(), f, "grpc.parent.grpc.service", "fields of the inbound call with the same value must be dropped")
+	assert.NotContains(s.T(), f, "grpc.parent.system", "fields of the inbound call with the same value must be dropped")
+	for _, line := range bytes.Split(bytes.TrimSpace(raw), []byte("\n")) {
+		assert.Empty(s.T(), duplicateFieldNames(s.T(), line), "no field must be logged twice")
+	}
+}
+
+func (s *slogClientContextLoggerSuite) TestPing_WithoutContextLogger() {
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "there must be not be an error on a successful call")
+	assert.Empty(s.T(), s.getOutputJSONs(), "calls without a context logger must use the interceptor logger")
+}
+
+func TestSlogClientTraceContextSuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skip("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+	opts := []grpc_slog.Option{
+		grpc_slog.WithTraceContext(),
+	}
+	b := newBaseSlogSuite(t)
+	b.log = b.log.Leveled(slog.LevelDebug)
+	b.InterceptorTestSuite.ClientOpts = []grpc.DialOption{
+		grpc.WithUnaryInterceptor(grpc_slog.UnaryClientInterceptor(b.log, opts...)),
+		grpc.WithStreamInterceptor(grpc_slog.StreamClientInterceptor(b.log, opts...)),
+	}
+	suite.Run(t, &slogClientTraceContextSuite{b})
+}
+
+type slogClientTraceContextSuite struct {
+	*slogBaseSuite
+}
+
+func (s *slogClientTraceContextSuite) TestPing_HasTraceContext() {
+	ctx := metadata.AppendToOutgoingContext(s.SimpleCtx(), "traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	_, err := s.Client.Ping(ctx, goodPing)
+	require.NoError(s.T(), err, "there must be not be an error on a successful call")
+
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 1, "one log statement should be logged")
+
+	// Get slog fields.
+	f := msgs[0]["fields"].(map[string]interface{})
+
+	assert.Equal(s.T(), f["trace_id"], "4bf92f3577b34da6a3ce929d0e0e4736", "the trace ID must be logged")
+	assert.Equal(s.T(), f["span_id"], "00f067aa0ba902b7", "the span ID must be logged")
+	assert.Equal(s.T(), f["trace_flags"], "00", "the trace flags must be logged")
+}
+
+func TestSlogClientRequestIDSuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skip("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+	opts := []grpc_slog.Option{
+		grpc_slog.WithRequestID("x-correlation-id"),
+		grpc_slog.WithMetadataAllowlist("x-correlation-id"),
+	}
+	b := newBaseSlogSuite(t)
+	b.log = b.log.Leveled(slog.LevelDebug)
+	b.InterceptorTestSuite.ClientOpts = []grpc.DialOption{
+		grpc.WithUnaryInterceptor(grpc_slog.UnaryClientInterceptor(b.log, opts...)),
+		grpc.WithStreamInterceptor(grpc_slog.StreamClientInterceptor(b.log, opts...)),
+	}
+	suite.Run(t, &slogClientRequestIDSuite{b})
+}
+
+type slogClientRequestIDSuite struct {
+	*slogBaseSuite
+}
+
+func (s *slogClientRequestIDSuite) TestPing_ForwardsRequestID() {
+	ctx := grpc_slog.ContextWithRequestID(s.SimpleCtx(), "abc")
+	_, err := s.Client.Ping(ctx, goodPing)
+	require.NoError(s.T(), err, "there must be not be an error on a successful call")
+
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 1, "one log statement should be logged")
+
+	// Get slog fields.
+	f := msgs[0]["fields"].(map[string]interface{})
+
+	assert.Equal(s.T(), f["request_id"], "abc", "the request ID must be logged")
+	assert.Equal(s.T(), f["grpc.request.metadata.x-correlation-id"], "abc", "the request ID must be forwarded in the outgoing metadata")
+}
@@ -3,6 +3,8 @@ package ctxslog
 import (
 	"context"
 	"io/ioutil"
+	"reflect"
+	"sync"
 
 	"cdr.dev/slog"
 	"cdr.dev/slog/sloggers/sloghuman"
@@ -13,37 +15,194 @@ type ctxMarker struct{}
 
 type ctxLogger struct {
 	logger slog.Logger
+	// parent is the holder of the enclosing scope, nil for the call-scoped holder created by ToContext.
+	parent *ctxLogger
+	// transparent holders, derived with Named or WithLevel, do not open a field scope: their fields are added to the
+	// nearest enclosing scope.
+	transparent bool
+
+	mu     sync.Mutex
 	fields []slog.Field
 }
 
 var (
 	ctxMarkerKey = &ctxMarker{}
+
+	defaultMu     sync.RWMutex
+	defaultLogger = sloghuman.Make(ioutil.Discard)
 )
 
+// SetDefault sets the Logger returned by Extract for contexts that do not hold a call-scoped Logger, such as the
+// contexts of background jobs and tests. By default these logs are discarded.
+func SetDefault(logger slog.Logger) {
+	defaultMu.Lock()
+	defaultLogger = logger
+	defaultMu.Unlock()
+}
+
+// Default returns the Logger set by SetDefault.
+func Default() slog.Logger {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultLogger
+}
+
+// allFields returns the fields of all enclosing scopes followed by the fields of this scope.
+func (l *ctxLogger) allFields() []slog.Field {
+	var fields []slog.Field
+	if l.parent != nil {
+		fields = l.parent.allFields()
+	}
+	l.mu.Lock()
+	fields = append(fields, l.fields...)
+	l.mu.Unlock()
+	return fields
+}
+
+func (l *ctxLogger) addFields(fields []slog.Field) {
+	l.mu.Lock()
+	l.fields = append(l.fields, fields...)
+	l.mu.Unlock()
+}
+
+// scope returns the holder of the nearest field scope, which is l unless l is transparent.
+func (l *ctxLogger) scope() *ctxLogger {
+	for l.transparent {
+		l = l.parent
+	}
+	return l
+}
+
+// root returns the call-scoped holder.
+func (l *ctxLogger) root() *ctxLogger {
+	for l.parent != nil {
+		l = l.parent
+	}
+	return l
+}
+
 // AddFields adds fields to the logger.
+//
+// When ctx was derived with With, the fields are only added to that scope, even if ctx was then derived with Named or
+// WithLevel. Use Promote to add fields to the call-scoped logger instead.
+//
+// It is safe to call AddFields and Extract concurrently from multiple goroutines sharing the same context.
 func AddFields(ctx context.Context, fields ...slog.Field) {
 	l, ok := ctx.Value(ctxMarkerKey).(*ctxLogger)
 	if !ok || l == nil {
 		return
 	}
-	l.fields = append(l.fields, fields...)
+	l.scope().addFields(fields)
 }
 
-// Extract takes the call-scoped Logger from grpc_slog middleware.
+// Promote adds fields to the call-scoped logger, even when ctx was derived with With. Promoted fields are present on
+// the completion log line of the call and on every logger extracted afterwards.
+func Promote(ctx context.Context, fields ...slog.Field) {
+	l, ok := ctx.Value(ctxMarkerKey).(*ctxLogger)
+	if !ok || l == nil {
+		return
+	}
+	l.root().addFields(fields)
+}
+
+// With returns a derived context whose extracted logger includes the given fields in addition to the fields of ctx.
+// The fields of ctx, and so the completion log line of the call, are left untouched.
 //
-// It always returns a Logger that has all the grpc_ctxtags updated.
-func Extract(ctx context.Context) slog.Logger {
+// If ctx does not hold a logger, ctx is returned as is.
+func With(ctx context.Context, fields ...slog.Field) context.Context {
+	return derive(ctx, func(l slog.Logger) slog.Logger { return l }, fields, false)
+}
+
+// Named returns a derived context whose extracted logger has the given name appended to its names, e.g. to name the
+// component of a handler that is logging. Fields added to the derived context are added to the scope of ctx.
+//
+// If ctx does not hold a logger, ctx is returned as is.
+func Named(ctx context.Context, name string) context.Context {
+	return derive(ctx, func(l slog.Logger) slog.Logger { return l.Named(name) }, nil, true)
+}
+
+// WithLevel returns a derived context whose extracted logger logs at the given minimum level, e.g. to temporarily
+// raise the verbosity of a single call. Fields added to the derived context are added to the scope of ctx.
+//
+// If ctx does not hold a logger, ctx is returned as is.
+func WithLevel(ctx context.Context, level slog.Level) context.Context {
+	return derive(ctx, func(l slog.Logger) slog.Logger { return l.Leveled(level) }, nil, true)
+}
+
+// derive returns a context holding a child of the logger in ctx, with the logger modified by f and the given fields.
+// A transparent child does not open a new field scope.
+func derive(ctx context.Context, f func(slog.Logger) slog.Logger, fields []slog.Field, transparent bool) context.Context {
 	l, ok := ctx.Value(ctxMarkerKey).(*ctxLogger)
 	if !ok || l == nil {
-		return sloghuman.Make(ioutil.Discard)
+		return ctx
 	}
+	child := &ctxLogger{
+		logger:      f(l.logger),
+		parent:      l,
+		transparent: transparent,
+		fields:      append([]slog.Field(nil), fields...),
+	}
+	return context.WithValue(ctx, ctxMarkerKey, child)
+}
+
+// Has reports whether ctx holds a call-scoped Logger.
+func Has(ctx context.Context) bool {
+	l, ok := ctx.Value(ctxMarkerKey).(*ctxLogger)
+	return ok && l != nil
+}
+
+// Extract takes the call-scoped Logger from grpc_slog middleware.
+//
+// It always returns a Logger that has all the grpc_ctxtags updated. If ctx does not hold a call-scoped Logger, the
+// Logger set by SetDefault is returned instead. Use Has to check whether ctx holds one.
+func Extract(ctx context.Context) slog.Logger {
+	return ExtractOr(ctx, Default())
+}
+
+// ExtractOr is like Extract, but returns the fallback Logger if ctx does not hold a call-scoped Logger.
+func ExtractOr(ctx context.Context, fallback slog.Logger) slog.Logger {
 	// Add grpc_ctxtags tags metadata until now.
 	fields := TagsToFields(ctx)
+	l, ok := ctx.Value(ctxMarkerKey).(*ctxLogger)
+	if !ok || l == nil {
+		return fallback.With(fields...)
+	}
 	// Add slog fields added until now.
-	fields = append(fields, l.fields...)
+	fields = append(fields, l.allFields()...)
 	return l.logger.With(fields...)
 }
 
+// ParentFieldPrefix is the prefix given by ExtractWith to the fields of the context that are replaced by a field of a
+// different value, e.g. `grpc.parent.grpc.method` for the method of the inbound call.
+const ParentFieldPrefix = "grpc.parent."
+
+// ExtractWith is like Extract, but adds the given fields after the fields of ctx, e.g. to log an outbound call made
+// while handling an inbound one. A field of ctx is dropped when one of the given fields has the same name and value,
+// and is renamed with ParentFieldPrefix when the value differs, so that no key is logged twice.
+//
+// Fields set on the Logger passed to ToContext cannot be inspected, so they are kept as is.
+func ExtractWith(ctx context.Context, fields ...slog.Field) slog.Logger {
+	l, ok := ctx.Value(ctxMarkerKey).(*ctxLogger)
+	if !ok || l == nil {
+		return Default().With(append(TagsToFields(ctx), fields...)...)
+	}
+	values := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		values[f.Name] = f.Value
+	}
+	var merged []slog.Field
+	for _, f := range append(TagsToFields(ctx), l.allFields()...) {
+		v, ok := values[f.Name]
+		switch {
+		case !ok:
+			merged = append(merged, f)
+		case !reflect.DeepEqual(v, f.Value):
+			merged = append(merged, slog.F(ParentFieldPrefix+f.Name, f.Value))
+		}
+	}
+	return l.logger.With(append(merged, fields...)...)
+}
+
 // TagsToFields transforms the Tags on the supplied context into slog fields.
 func TagsToFields(ctx context.Context) []slog.Field {
 	var fields []slog.Field
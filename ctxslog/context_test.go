@@ -0,0 +1,162 @@
+package ctxslog_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+
+	"cdr.dev/slog"
+	"cdr.dev/slog/sloggers/slogjson"
+	"github.com/hassieswift621/slog-grpc-mw/ctxslog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syncBuffer is a bytes.Buffer that is safe for concurrent writes.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Write(p)
+}
+
+func lastLineFields(t *testing.T, b *syncBuffer) map[string]interface{} {
+	lines := bytes.Split(bytes.TrimSpace(b.b.Bytes()), []byte("\n"))
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(lines[len(lines)-1], &entry), "log output must be valid JSON")
+	return entry["fields"].(map[string]interface{})
+}
+
+func TestAddFields_Concurrent(t *testing.T) {
+	b := &syncBuffer{}
+	ctx := ctxslog.ToContext(context.Background(), slogjson.Make(b))
+
+	const goroutines = 50
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctxslog.AddFields(ctx, slog.F(fmt.Sprintf("field_%d", i), i))
+			ctxslog.Extract(ctx).Info(ctx, "concurrent log")
+		}(i)
+	}
+	wg.Wait()
+
+	ctxslog.Extract(ctx).Info(ctx, "final log")
+	f := lastLineFields(t, b)
+	for i := 0; i < goroutines; i++ {
+		assert.EqualValues(t, i, f[fmt.Sprintf("field_%d", i)], "all fields added concurrently must be present")
+	}
+}
+
+func TestExtract_WithoutLogger(t *testing.T) {
+	ctxslog.AddFields(context.Background(), slog.F("ignored", true))
+	assert.NotPanics(t, func() {
+		ctxslog.Extract(context.Background()).Info(context.Background(), "discarded")
+	}, "extracting from a context without a logger must not panic")
+}
+
+func TestWith_ScopesFields(t *testing.T) {
+	b := &syncBuffer{}
+	ctx := ctxslog.ToContext(context.Background(), slogjson.Make(b))
+	ctxslog.AddFields(ctx, slog.F("call_field", "call"))
+
+	scoped := ctxslog.With(ctx, slog.F("scoped_field", "scoped"))
+	ctxslog.AddFields(scoped, slog.F("scoped_added", "scoped"))
+	ctxslog.Promote(scoped, slog.F("promoted_field", "promoted"))
+
+	ctxslog.Extract(scoped).Info(scoped, "scoped log")
+	f := lastLineFields(t, b)
+	assert.Equal(t, "call", f["call_field"], "scoped loggers must contain the fields of the call")
+	assert.Equal(t, "scoped", f["scoped_field"], "scoped loggers must contain the scoped fields")
+	assert.Equal(t, "scoped", f["scoped_added"], "scoped loggers must contain fields added to the scope")
+	assert.Equal(t, "promoted", f["promoted_field"], "scoped loggers must contain promoted fields")
+
+	ctxslog.Extract(ctx).Info(ctx, "call log")
+	f = lastLineFields(t, b)
+	assert.Equal(t, "call", f["call_field"], "the call logger must contain the fields of the call")
+	assert.Equal(t, "promoted", f["promoted_field"], "the call logger must contain promoted fields")
+	assert.NotContains(t, f, "scoped_field", "the call logger must not contain scoped fields")
+	assert.NotContains(t, f, "scoped_added", "the call logger must not contain fields added to the scope")
+}
+
+func TestNamedAndWithLevel(t *testing.T) {
+	b := &syncBuffer{}
+	ctx := ctxslog.ToContext(context.Background(), slogjson.Make(b).Leveled(slog.LevelInfo))
+
+	debugCtx := ctxslog.WithLevel(ctxslog.Named(ctx, "db"), slog.LevelDebug)
+	ctxslog.Extract(debugCtx).Debug(debugCtx, "debug log")
+	ctxslog.Extract(ctx).Debug(ctx, "discarded debug log")
+
+	lines := bytes.Split(bytes.TrimSpace(b.b.Bytes()), []byte("\n"))
+	require.Len(t, lines, 1, "only the debug line of the derived context must be logged")
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(lines[0], &entry), "log output must be valid JSON")
+	assert.Equal(t, "debug log", entry["msg"], "the derived context must log at the overridden level")
+	assert.Equal(t, []interface{}{"db"}, entry["logger_names"], "the derived context must be named")
+}
+
+func TestNamedAndWithLevel_AddFieldsToScope(t *testing.T) {
+	b := &syncBuffer{}
+	ctx := ctxslog.ToContext(context.Background(), slogjson.Make(b))
+
+	derived := ctxslog.WithLevel(ctxslog.Named(ctx, "db"), slog.LevelDebug)
+	ctxslog.AddFields(derived, slog.F("user_id", 42))
+	scoped := ctxslog.Named(ctxslog.With(ctx, slog.F("scoped_field", "scoped")), "cache")
+	ctxslog.AddFields(scoped, slog.F("scoped_added", "scoped"))
+
+	ctxslog.Extract(scoped).Info(scoped, "scoped log")
+	f := lastLineFields(t, b)
+	assert.Equal(t, "scoped", f["scoped_added"], "fields added to a named context must be added to the enclosing scope")
+
+	// The completion line of the call is logged with the call-scoped logger.
+	ctxslog.Extract(ctx).Info(ctx, "finished unary call")
+	f = lastLineFields(t, b)
+	assert.EqualValues(t, 42, f["user_id"], "fields added to named and leveled contexts must reach the completion line")
+	assert.NotContains(t, f, "scoped_added", "fields added within a With scope must stay in that scope")
+}
+
+func TestExtract_Fallback(t *testing.T) {
+	ctx := context.Background()
+	assert.False(t, ctxslog.Has(ctx), "a background context must not hold a logger")
+
+	b := &syncBuffer{}
+	ctxslog.ExtractOr(ctx, slogjson.Make(b)).Info(ctx, "fallback log")
+	assert.Contains(t, b.b.String(), "fallback log", "the fallback logger must be used")
+
+	d := &syncBuffer{}
+	ctxslog.SetDefault(slogjson.Make(d))
+	defer ctxslog.SetDefault(slogjson.Make(ioutil.Discard))
+	ctxslog.Extract(ctx).Info(ctx, "default log")
+	assert.Contains(t, d.b.String(), "default log", "the default logger must be used")
+
+	callCtx := ctxslog.ToContext(ctx, slogjson.Make(b))
+	assert.True(t, ctxslog.Has(callCtx), "a context with a logger must hold it")
+	ctxslog.ExtractOr(callCtx, slogjson.Make(d)).Info(callCtx, "call log")
+	assert.Contains(t, b.b.String(), "call log", "the call-scoped logger must be preferred")
+	assert.NotContains(t, d.b.String(), "call log", "the fallback must not be used when there is a call-scoped logger")
+}
+
+func TestExtractWith_ReplacesFields(t *testing.T) {
+	b := &syncBuffer{}
+	ctx := ctxslog.ToContext(context.Background(), slogjson.Make(b))
+	ctxslog.AddFields(ctx, slog.F("system", "grpc"), slog.F("span.kind", "server"), slog.F("call_field", "call"))
+
+	ctxslog.ExtractWith(ctx, slog.F("system", "grpc"), slog.F("span.kind", "client")).Info(ctx, "outbound log")
+	f := lastLineFields(t, b)
+	assert.Equal(t, "call", f["call_field"], "the fields of the context must be kept")
+	assert.Equal(t, "client", f["span.kind"], "the given fields must be added")
+	assert.Equal(t, "server", f[ctxslog.ParentFieldPrefix+"span.kind"], "replaced fields with a different value must be prefixed")
+	assert.NotContains(t, f, ctxslog.ParentFieldPrefix+"system", "replaced fields with the same value must be dropped")
+	assert.Equal(t, 1, strings.Count(b.b.String(), `"span.kind"`), "replaced fields must not be logged twice")
+}
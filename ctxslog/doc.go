@@ -9,6 +9,21 @@ You can use `ctxslog.Extract` to log into a request-scoped `slog.Logger` instanc
 As `ctxslog.Extract` will iterate all tags on from `grpc_ctxtags` it is therefore expensive so it is advised that you
 extract once at the start of the function from the context and reuse it for the remainder of the function (see examples).
 
+`ctxslog.AddFields` and `ctxslog.Extract` are safe for concurrent use, so handlers may pass the request context to
+goroutines that add fields or log while the handler is running.
+
+`ctxslog.With` derives a context with fields scoped to a single sub-operation of a handler, which are not added to the
+completion log line of the call. `ctxslog.Promote` adds fields to the call-scoped logger from any derived context.
+Similarly, `ctxslog.Named` and `ctxslog.WithLevel` derive contexts whose extracted loggers are named or log at a
+different minimum level.
+
+When the context does not hold a call-scoped logger, `ctxslog.Extract` returns the logger set by `ctxslog.SetDefault`,
+which discards all logs by default. `ctxslog.ExtractOr` takes the fallback logger as an argument instead, and
+`ctxslog.Has` reports whether the context holds a call-scoped logger.
+
+`ctxslog.ExtractWith` adds fields that replace the fields of the context of the same name, e.g. to log the outbound
+calls made by a handler without logging keys such as `span.kind` twice.
+
 Please see examples and tests for examples of use.
 */
 package ctxslog
@@ -3,6 +3,7 @@ package ctxslog_test
 import (
 	"context"
 
+	"cdr.dev/slog"
 	"github.com/hassieswift621/slog-grpc-mw/ctxslog"
 
 	grpc_ctxtags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
@@ -22,3 +23,16 @@ func ExampleExtract_unary() {
 		return &pb_testproto.PingResponse{Value: ping.Value}, nil
 	}
 }
+
+// Unary handler that adds fields to a single sub-operation without adding them to the completion log line of the call.
+func ExampleWith() {
+	_ = func(ctx context.Context, ping *pb_testproto.PingRequest) (*pb_testproto.PingResponse, error) {
+		// Fields added to the derived context are only present on loggers extracted from it.
+		dbCtx := ctxslog.With(ctx, slog.F("db.table", "pings"))
+		ctxslog.Extract(dbCtx).Info(dbCtx, "querying pings")
+
+		// Promoted fields are added to the call-scoped logger and so are present on the completion log line.
+		ctxslog.Promote(dbCtx, slog.F("db.rows", 1))
+		return &pb_testproto.PingResponse{Value: ping.Value}, nil
+	}
+}
@@ -11,11 +11,29 @@ If a deadline is present on the gRPC request the grpc.request.deadline tag is po
 is a string representing the time (RFC3339) when the current call will expire.
 
 This package also implements request and response *payload* logging, both for server-side and client-side. These will be
-logged as structured `jsonpb` fields for every message received/sent (both unary and streaming). For that please use
+logged as structured `protojson` fields for every message received/sent (both unary and streaming). For that please use
 `Payload*Interceptor` functions. Please note that the user-provided function that determines whether to log
 the full request/response payload needs to be written with care, as this can significantly slow down gRPC.
+The serialization can be customized with `WithPayloadMarshalOptions`. Sensitive fields can be masked in logged payloads
+with `WithRedactedFields`, `WithRedactionExtension` or `WithFieldRedactor`.
 
-Slog can also be made as a backend for gRPC library internals. For that use `ReplaceGrpcLoggerV2`.
+Options can be overridden for the calls of specific methods with `WithMethodOptions`, which accepts a full method name
+or a service wildcard such as `/pkg.Service/*`, and `WithMethodRegexpOptions`. This allows a single interceptor chain to
+use different levels, sampling, slow call thresholds, fields (`WithFields`) or payload logging (`WithPayloadLogging`)
+for different services.
+
+Panics in handlers can be logged through the call-scoped logger with `RecoveryUnaryServerInterceptor` and
+`RecoveryStreamServerInterceptor`, which convert them into `codes.Internal` errors.
+
+Slog can also be made as a backend for gRPC library internals. For that use `ReplaceGrpcLoggerV2`. Like grpc's default
+logger, it reads the minimum severity and the verbosity from the GRPC_GO_LOG_SEVERITY_LEVEL and
+GRPC_GO_LOG_VERBOSITY_LEVEL environment variables, which can be overridden with `WithGrpcLogSeverity` and
+`WithGrpcLogVerbosity`. Only errors are logged when the severity is unset. The component and channel tags of the logs
+are logged as the grpc.component, grpc.channel_id and grpc.subchannel_id fields, and `WithGrpcComponentSeverity` sets
+the minimum severity of a single component. Fatal logs can be routed through `WithGrpcFatalHook` instead of exiting the
+process, and the function returned by `ReplaceGrpcLoggerV2` reinstates the previous logger. Bursts of repeated logs,
+such as dial errors while a backend is down, can be collapsed with `WithGrpcLogDeduplication` and limited with
+`WithGrpcLogRateLimit`.
 
 *Server Interceptor*
 Below is a JSON formatted example of a log that would be logged by the server interceptor:
@@ -38,6 +56,8 @@ Below is a JSON formatted example of a log that would be logged by the server in
 			"grpc.start_time": "2006-01-02T15:04:05Z07:00",			// string	RFC3339 representation of the start time
 			"grpc.time_ms": 1.234,									// float32	run time of the call in ms
 			"peer.address": "127.0.0.1:55948"						// string	IP address of calling party and the port which the call is incoming on
+			"peer.cert.subject": "CN=client"						// string	subject of the verified client certificate (mTLS only)
+			"peer.tls.version": "TLS 1.3"							// string	negotiated TLS version (TLS only)
 			"span.kind": "server",									// string	client | server
 			"system": "grpc"										// string
 		}
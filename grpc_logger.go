@@ -3,76 +3,303 @@ package grpc_slog
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"cdr.dev/slog"
 	"google.golang.org/grpc/grpclog"
 )
 
+const (
+	// GrpcLogSeverityEnvVar is the environment variable read by grpc's default logger to set the minimum severity.
+	GrpcLogSeverityEnvVar = "GRPC_GO_LOG_SEVERITY_LEVEL"
+	// GrpcLogVerbosityEnvVar is the environment variable read by grpc's default logger to set the verbosity.
+	GrpcLogVerbosityEnvVar = "GRPC_GO_LOG_VERBOSITY_LEVEL"
+)
+
+var (
+	// grpcLoggerMu guards currentGrpcLogger, the logger installed by ReplaceGrpcLoggerV2, nil for grpc's default logger.
+	grpcLoggerMu      sync.Mutex
+	currentGrpcLogger grpclog.LoggerV2
+)
+
 type slogGrpcLoggerV2 struct {
-	logger    slog.Logger
-	verbosity int
+	logger            slog.Logger
+	severity          slog.Level
+	componentSeverity map[string]slog.Level
+	verbosity         int
+	fatalHook         GrpcFatalHook
+	limiter           *grpcLogLimiter
 }
 
-// ReplaceGrpcLoggerV2 replaces the grpc_log.LoggerV2 with the provided logger.
-func ReplaceGrpcLoggerV2(logger slog.Logger) {
-	ReplaceGrpcLoggerV2WithVerbosity(logger, 0)
+type grpcLoggerOptions struct {
+	severity          slog.Level
+	componentSeverity map[string]slog.Level
+	verbosity         int
+	fatalHook         GrpcFatalHook
+	dedupWindow       time.Duration
+	rateLimits        map[slog.Level]int
 }
 
-// ReplaceGrpcLoggerV2WithVerbosity replaces the grpc_log.LoggerV2 with the provided logger and verbosity.
-func ReplaceGrpcLoggerV2WithVerbosity(logger slog.Logger, verbosity int) {
-	grpcLogger := &slogGrpcLoggerV2{
-		logger:    logger.With(SystemField, slog.F("grpc_log", true)),
-		verbosity: verbosity,
+// GrpcFatalHook handles the Fatal logs of gRPC instead of exiting the process.
+type GrpcFatalHook func(msg string)
+
+// GrpcFatalPanic is a GrpcFatalHook that panics with the message of the Fatal log, e.g. to capture library fatals in
+// tests.
+func GrpcFatalPanic(msg string) {
+	panic(msg)
+}
+
+// GrpcLoggerOption configures the logger installed by ReplaceGrpcLoggerV2.
+type GrpcLoggerOption func(*grpcLoggerOptions)
+
+// WithGrpcLogSeverity sets the minimum severity of the gRPC internal logs that are logged, e.g. slog.LevelWarn to drop
+// Info logs. It takes precedence over the GRPC_GO_LOG_SEVERITY_LEVEL environment variable.
+func WithGrpcLogSeverity(level slog.Level) GrpcLoggerOption {
+	return func(o *grpcLoggerOptions) {
+		o.severity = level
+	}
+}
+
+// WithGrpcLogVerbosity sets the verbosity reported to gRPC, which gRPC uses to decide whether to log verbose Info
+// logs. It takes precedence over the GRPC_GO_LOG_VERBOSITY_LEVEL environment variable.
+func WithGrpcLogVerbosity(verbosity int) GrpcLoggerOption {
+	return func(o *grpcLoggerOptions) {
+		o.verbosity = verbosity
+	}
+}
+
+// WithGrpcFatalHook makes the Fatal logs of gRPC be logged at slog.LevelCritical and then passed to hook, instead of
+// being logged at slog.LevelFatal and exiting the process.
+//
+// Note that the grpclog functions used by gRPC exit the process themselves once the logger returns, so the hook must
+// panic, as GrpcFatalPanic does, to prevent the process from exiting. Otherwise it can be used to flush logs and
+// telemetry before the process exits.
+func WithGrpcFatalHook(hook GrpcFatalHook) GrpcLoggerOption {
+	return func(o *grpcLoggerOptions) {
+		o.fatalHook = hook
 	}
+}
+
+// evaluateGrpcLoggerOpt returns the options of the gRPC logger. The defaults are read from the environment the way
+// grpc's default logger does, so only errors are logged when GRPC_GO_LOG_SEVERITY_LEVEL is unset.
+func evaluateGrpcLoggerOpt(opts []GrpcLoggerOption) *grpcLoggerOptions {
+	o := &grpcLoggerOptions{
+		severity: slog.LevelError,
+	}
+	switch strings.ToUpper(os.Getenv(GrpcLogSeverityEnvVar)) {
+	case "WARNING":
+		o.severity = slog.LevelWarn
+	case "INFO":
+		o.severity = slog.LevelInfo
+	}
+	if v, err := strconv.Atoi(os.Getenv(GrpcLogVerbosityEnvVar)); err == nil {
+		o.verbosity = v
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// NewGrpcLoggerV2 returns a grpclog.LoggerV2 logging to the provided logger. It also implements grpclog.DepthLoggerV2,
+// so the caller of the entries is the gRPC source line that logged.
+//
+// By default the minimum severity and the verbosity are read from the GRPC_GO_LOG_SEVERITY_LEVEL and
+// GRPC_GO_LOG_VERBOSITY_LEVEL environment variables, as done by grpc's default logger: only errors are logged when the
+// severity is unset. Use WithGrpcLogSeverity to log Info and Warning logs regardless of the environment.
+func NewGrpcLoggerV2(logger slog.Logger, opts ...GrpcLoggerOption) grpclog.LoggerV2 {
+	o := evaluateGrpcLoggerOpt(opts)
+	logger = logger.With(SystemField, slog.F("grpc_log", true))
+	return &slogGrpcLoggerV2{
+		logger:            logger,
+		severity:          o.severity,
+		componentSeverity: o.componentSeverity,
+		verbosity:         o.verbosity,
+		fatalHook:         o.fatalHook,
+		limiter:           newGrpcLogLimiter(logger, o),
+	}
+}
+
+// ReplaceGrpcLoggerV2 replaces the grpc_log.LoggerV2 with the provided logger. See NewGrpcLoggerV2 for the defaults.
+//
+// The returned function reinstates the previous logger. As grpclog does not expose its logger, the previous logger is
+// only known when it was installed by ReplaceGrpcLoggerV2; otherwise a logger equivalent to grpc's default logger is
+// reinstated. Restore functions must be called in the reverse order of the replacements: the function is a no-op when
+// the logger it installed has since been replaced or restored, so that it does not reinstate a logger that is no longer
+// in use.
+//
+// It must be called before any gRPC functions, as the grpclog logger is not safe for concurrent replacement. The same
+// applies to the restore function.
+func ReplaceGrpcLoggerV2(logger slog.Logger, opts ...GrpcLoggerOption) (restore func()) {
+	grpcLogger := NewGrpcLoggerV2(logger, opts...)
+	grpcLoggerMu.Lock()
+	previous := currentGrpcLogger
+	currentGrpcLogger = grpcLogger
 	grpclog.SetLoggerV2(grpcLogger)
+	grpcLoggerMu.Unlock()
+	return func() {
+		grpcLoggerMu.Lock()
+		defer grpcLoggerMu.Unlock()
+		if currentGrpcLogger != grpcLogger {
+			return
+		}
+		currentGrpcLogger = previous
+		if previous == nil {
+			grpclog.SetLoggerV2(newDefaultGrpcLogger())
+			return
+		}
+		grpclog.SetLoggerV2(previous)
+	}
+}
+
+// ReplaceGrpcLoggerV2WithVerbosity replaces the grpc_log.LoggerV2 with the provided logger and verbosity.
+func ReplaceGrpcLoggerV2WithVerbosity(logger slog.Logger, verbosity int) (restore func()) {
+	return ReplaceGrpcLoggerV2(logger, WithGrpcLogVerbosity(verbosity))
+}
+
+// newDefaultGrpcLogger returns a logger configured like grpc's default logger, which writes the logs of the severity
+// set by GRPC_GO_LOG_SEVERITY_LEVEL and above to stderr, ERROR by default.
+func newDefaultGrpcLogger() grpclog.LoggerV2 {
+	infoW, warningW, errorW := ioutil.Discard, ioutil.Discard, ioutil.Discard
+	switch strings.ToUpper(os.Getenv(GrpcLogSeverityEnvVar)) {
+	case "", "ERROR":
+		errorW = os.Stderr
+	case "WARNING":
+		warningW = os.Stderr
+	case "INFO":
+		infoW = os.Stderr
+	}
+	v, _ := strconv.Atoi(os.Getenv(GrpcLogVerbosityEnvVar))
+	return grpclog.NewLoggerV2WithVerbosity(infoW, warningW, errorW, v)
+}
+
+// callerSkip is the number of frames skipped by logDepth to reach the caller of the grpclog function that called the
+// adapter method: logDepth itself, the adapter method and the grpclog function.
+const callerSkip = 3
+
+// logDepth logs msg at the given level if it is not below the minimum severity of its component, reporting the caller
+// depth frames above the caller of the grpclog function as the location of the entry.
+func (l *slogGrpcLoggerV2) logDepth(depth int, level slog.Level, msg string) {
+	if level < l.severity && len(l.componentSeverity) == 0 {
+		return
+	}
+	component, fields, text := parseGrpcLog(msg)
+	if level < l.minSeverity(component) {
+		return
+	}
+	entry := slog.SinkEntry{
+		Time:    time.Now().UTC(),
+		Level:   level,
+		Message: text,
+		Fields:  fields,
+	}
+	if pc, file, line, ok := runtime.Caller(depth + callerSkip); ok {
+		entry.File = file
+		entry.Line = line
+		if fn := runtime.FuncForPC(pc); fn != nil {
+			entry.Func = fn.Name()
+		}
+	}
+	if l.limiter != nil && !l.limiter.allow(msg, &entry) {
+		return
+	}
+	if level == slog.LevelFatal && l.fatalHook != nil {
+		entry.Level = slog.LevelCritical
+	}
+	l.logger.LogEntry(context.Background(), entry)
+	if level >= slog.LevelError {
+		l.logger.Sync()
+	}
+	if level == slog.LevelFatal {
+		if l.fatalHook != nil {
+			l.fatalHook(text)
+			return
+		}
+		os.Exit(1)
+	}
+}
+
+// minSeverity returns the minimum severity of the logs of the given component.
+func (l *slogGrpcLoggerV2) minSeverity(component string) slog.Level {
+	if level, ok := l.componentSeverity[component]; ok {
+		return level
+	}
+	return l.severity
+}
+
+// sprintln formats args in the manner of fmt.Println, without the trailing newline.
+func sprintln(args ...interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
 }
 
 func (l *slogGrpcLoggerV2) Info(args ...interface{}) {
-	l.logger.Info(context.Background(), fmt.Sprint(args...))
+	l.logDepth(0, slog.LevelInfo, fmt.Sprint(args...))
 }
 
 func (l *slogGrpcLoggerV2) Infoln(args ...interface{}) {
-	l.logger.Info(context.Background(), fmt.Sprint(args...))
+	l.logDepth(0, slog.LevelInfo, fmt.Sprint(args...))
 }
 
 func (l *slogGrpcLoggerV2) Infof(format string, args ...interface{}) {
-	l.logger.Info(context.Background(), fmt.Sprintf(format, args...))
+	l.logDepth(0, slog.LevelInfo, fmt.Sprintf(format, args...))
+}
+
+func (l *slogGrpcLoggerV2) InfoDepth(depth int, args ...interface{}) {
+	l.logDepth(depth, slog.LevelInfo, sprintln(args...))
 }
 
 func (l *slogGrpcLoggerV2) Warning(args ...interface{}) {
-	l.logger.Warn(context.Background(), fmt.Sprint(args...))
+	l.logDepth(0, slog.LevelWarn, fmt.Sprint(args...))
 }
 
 func (l *slogGrpcLoggerV2) Warningln(args ...interface{}) {
-	l.logger.Warn(context.Background(), fmt.Sprint(args...))
+	l.logDepth(0, slog.LevelWarn, fmt.Sprint(args...))
 }
 
 func (l *slogGrpcLoggerV2) Warningf(format string, args ...interface{}) {
-	l.logger.Warn(context.Background(), fmt.Sprintf(format, args...))
+	l.logDepth(0, slog.LevelWarn, fmt.Sprintf(format, args...))
+}
+
+func (l *slogGrpcLoggerV2) WarningDepth(depth int, args ...interface{}) {
+	l.logDepth(depth, slog.LevelWarn, sprintln(args...))
 }
 
 func (l *slogGrpcLoggerV2) Error(args ...interface{}) {
-	l.logger.Error(context.Background(), fmt.Sprint(args...))
+	l.logDepth(0, slog.LevelError, fmt.Sprint(args...))
 }
 
 func (l *slogGrpcLoggerV2) Errorln(args ...interface{}) {
-	l.logger.Error(context.Background(), fmt.Sprint(args...))
+	l.logDepth(0, slog.LevelError, fmt.Sprint(args...))
 }
 
 func (l *slogGrpcLoggerV2) Errorf(format string, args ...interface{}) {
-	l.logger.Error(context.Background(), fmt.Sprintf(format, args...))
+	l.logDepth(0, slog.LevelError, fmt.Sprintf(format, args...))
+}
+
+func (l *slogGrpcLoggerV2) ErrorDepth(depth int, args ...interface{}) {
+	l.logDepth(depth, slog.LevelError, sprintln(args...))
 }
 
 func (l *slogGrpcLoggerV2) Fatal(args ...interface{}) {
-	l.logger.Fatal(context.Background(), fmt.Sprint(args...))
+	l.logDepth(0, slog.LevelFatal, fmt.Sprint(args...))
 }
 
 func (l *slogGrpcLoggerV2) Fatalln(args ...interface{}) {
-	l.logger.Fatal(context.Background(), fmt.Sprint(args...))
+	l.logDepth(0, slog.LevelFatal, fmt.Sprint(args...))
 }
 
 func (l *slogGrpcLoggerV2) Fatalf(format string, args ...interface{}) {
-	l.logger.Fatal(context.Background(), fmt.Sprintf(format, args...))
+	l.logDepth(0, slog.LevelFatal, fmt.Sprintf(format, args...))
+}
+
+func (l *slogGrpcLoggerV2) FatalDepth(depth int, args ...interface{}) {
+	l.logDepth(depth, slog.LevelFatal, sprintln(args...))
 }
 
 func (l *slogGrpcLoggerV2) V(level int) bool {
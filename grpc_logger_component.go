@@ -0,0 +1,71 @@
+package grpc_slog
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"cdr.dev/slog"
+)
+
+var (
+	// grpcComponentPrefix matches the component tag added by grpc's component loggers, e.g. `[transport] `.
+	grpcComponentPrefix = regexp.MustCompile(`^\[([\w-]+)\] ?`)
+	// grpcChannelPrefix matches the channel tag added by newer versions of grpc, e.g. `[Channel #1 SubChannel #2] `.
+	grpcChannelPrefix = regexp.MustCompile(`^\[Channel #(\d+)(?: SubChannel #(\d+))?\] ?`)
+	// grpcChannelRef matches the channel references formatted into channelz trace events, e.g. `Subchannel(id:2) created`.
+	grpcChannelRef = regexp.MustCompile(`(?i)\b(channel|subchannel)\(id:(\d+)\)`)
+)
+
+// WithGrpcComponentSeverity sets the minimum severity of the gRPC internal logs of the given component, e.g.
+// `WithGrpcComponentSeverity("transport", slog.LevelError)`, overriding the minimum severity of the other components.
+func WithGrpcComponentSeverity(component string, level slog.Level) GrpcLoggerOption {
+	return func(o *grpcLoggerOptions) {
+		if o.componentSeverity == nil {
+			o.componentSeverity = map[string]slog.Level{}
+		}
+		o.componentSeverity[component] = level
+	}
+}
+
+// parseGrpcLog extracts the component and channel tags of a gRPC internal log. It returns the component, the fields
+// describing the tags and the message without the leading tags.
+func parseGrpcLog(msg string) (string, []slog.Field, string) {
+	var component string
+	var fields []slog.Field
+	if m := grpcComponentPrefix.FindStringSubmatch(msg); m != nil {
+		component = m[1]
+		fields = append(fields, slog.F("grpc.component", component))
+		msg = msg[len(m[0]):]
+	}
+	if m := grpcChannelPrefix.FindStringSubmatch(msg); m != nil {
+		fields = appendChannelID(fields, "grpc.channel_id", m[1])
+		fields = appendChannelID(fields, "grpc.subchannel_id", m[2])
+		msg = msg[len(m[0]):]
+		return component, fields, msg
+	}
+	// References are kept in the message, as they are part of the sentence. Only the first reference of each kind is
+	// logged as a field, e.g. the sub-channel being created rather than its sibling in `Subchannel(id:3) replaces
+	// Subchannel(id:2)`.
+	var channel, subchannel bool
+	for _, m := range grpcChannelRef.FindAllStringSubmatch(msg, -1) {
+		if strings.EqualFold(m[1], "channel") {
+			if !channel {
+				fields = appendChannelID(fields, "grpc.channel_id", m[2])
+				channel = true
+			}
+		} else if !subchannel {
+			fields = appendChannelID(fields, "grpc.subchannel_id", m[2])
+			subchannel = true
+		}
+	}
+	return component, fields, msg
+}
+
+func appendChannelID(fields []slog.Field, name string, id string) []slog.Field {
+	v, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return fields
+	}
+	return append(fields, slog.F(name, v))
+}
@@ -0,0 +1,143 @@
+package grpc_slog
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"cdr.dev/slog"
+)
+
+// WithGrpcLogDeduplication collapses identical gRPC internal logs of the same severity logged within window of the
+// first one. The first log is logged right away, and the repeated ones are logged as a single line with a
+// `repeat_count` field when the window closes.
+func WithGrpcLogDeduplication(window time.Duration) GrpcLoggerOption {
+	return func(o *grpcLoggerOptions) {
+		o.dedupWindow = window
+	}
+}
+
+// WithGrpcLogRateLimit limits the gRPC internal logs of the given severity to perSecond lines per second. The excess
+// lines are dropped, and the number of dropped lines is logged as a `dropped_count` field on the next line of that
+// severity. Fatal logs are never limited.
+func WithGrpcLogRateLimit(level slog.Level, perSecond int) GrpcLoggerOption {
+	return func(o *grpcLoggerOptions) {
+		if o.rateLimits == nil {
+			o.rateLimits = map[slog.Level]int{}
+		}
+		o.rateLimits[level] = perSecond
+	}
+}
+
+type grpcLogKey struct {
+	level slog.Level
+	msg   string
+}
+
+// grpcLogRepeat counts the repetitions of a log within the deduplication window.
+type grpcLogRepeat struct {
+	entry slog.SinkEntry
+	count int
+}
+
+// grpcLogBucket counts the logs of a severity within the current one second window.
+type grpcLogBucket struct {
+	start   time.Time
+	count   int
+	dropped int
+}
+
+// grpcLogLimiter deduplicates and rate limits gRPC internal logs.
+type grpcLogLimiter struct {
+	logger     slog.Logger
+	window     time.Duration
+	rateLimits map[slog.Level]int
+
+	mu      sync.Mutex
+	repeats map[grpcLogKey]*grpcLogRepeat
+	buckets map[slog.Level]*grpcLogBucket
+}
+
+// newGrpcLogLimiter returns the limiter for the options, or nil when neither deduplication nor rate limiting is enabled.
+func newGrpcLogLimiter(logger slog.Logger, o *grpcLoggerOptions) *grpcLogLimiter {
+	if o.dedupWindow <= 0 && len(o.rateLimits) == 0 {
+		return nil
+	}
+	return &grpcLogLimiter{
+		logger:     logger,
+		window:     o.dedupWindow,
+		rateLimits: o.rateLimits,
+		repeats:    map[grpcLogKey]*grpcLogRepeat{},
+		buckets:    map[slog.Level]*grpcLogBucket{},
+	}
+}
+
+// allow reports whether entry should be logged now. rawMsg identifies the log for deduplication. It may add a
+// `dropped_count` field to the entry.
+func (d *grpcLogLimiter) allow(rawMsg string, entry *slog.SinkEntry) bool {
+	if entry.Level >= slog.LevelFatal {
+		return true
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.window > 0 {
+		key := grpcLogKey{level: entry.Level, msg: rawMsg}
+		if r, ok := d.repeats[key]; ok {
+			r.count++
+			return false
+		}
+		r := &grpcLogRepeat{entry: *entry}
+		d.repeats[key] = r
+		time.AfterFunc(d.window, func() { d.flush(key) })
+		if !d.take(entry) {
+			// The first log is reported with its repetitions.
+			r.count++
+			return false
+		}
+		return true
+	}
+	return d.take(entry)
+}
+
+// take consumes a line of the rate limit of the severity of entry. It must be called with d.mu held.
+func (d *grpcLogLimiter) take(entry *slog.SinkEntry) bool {
+	limit, ok := d.rateLimits[entry.Level]
+	if !ok {
+		return true
+	}
+	b, ok := d.buckets[entry.Level]
+	if !ok {
+		b = &grpcLogBucket{}
+		d.buckets[entry.Level] = b
+	}
+	now := time.Now()
+	if now.Sub(b.start) >= time.Second {
+		b.start = now
+		b.count = 0
+	}
+	if b.count >= limit {
+		b.dropped++
+		return false
+	}
+	b.count++
+	if b.dropped > 0 {
+		entry.Fields = append(entry.Fields, slog.F("dropped_count", b.dropped))
+		b.dropped = 0
+	}
+	return true
+}
+
+// flush logs the repetitions of the log identified by key when its deduplication window closes.
+func (d *grpcLogLimiter) flush(key grpcLogKey) {
+	d.mu.Lock()
+	r := d.repeats[key]
+	delete(d.repeats, key)
+	d.mu.Unlock()
+	if r == nil || r.count == 0 {
+		return
+	}
+	entry := r.entry
+	entry.Time = time.Now().UTC()
+	entry.Fields = append(append(slog.Map(nil), entry.Fields...), slog.F("repeat_count", r.count))
+	d.logger.LogEntry(context.Background(), entry)
+}
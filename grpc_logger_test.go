@@ -0,0 +1,314 @@
+package grpc_slog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"cdr.dev/slog"
+	"cdr.dev/slog/sloggers/slogjson"
+	grpc_testing "github.com/grpc-ecosystem/go-grpc-middleware/testing"
+	grpc_slog "github.com/hassieswift621/slog-grpc-mw"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/grpclog"
+)
+
+func decodeGrpcLogs(t *testing.T, b io.Reader) []map[string]interface{} {
+	var ret []map[string]interface{}
+	dec := json.NewDecoder(b)
+	for {
+		var val map[string]interface{}
+		err := dec.Decode(&val)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err, "failed decoding output from Slog JSON")
+		ret = append(ret, val)
+	}
+	return ret
+}
+
+func TestGrpcLoggerV2_Severity(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := grpc_slog.NewGrpcLoggerV2(slogjson.Make(b), grpc_slog.WithGrpcLogSeverity(slog.LevelWarn))
+
+	l.Info("info")
+	l.Warning("warning")
+	l.Errorf("error %d", 1)
+
+	msgs := decodeGrpcLogs(t, b)
+	require.Len(t, msgs, 2, "logs below the minimum severity must be dropped")
+	assert.Equal(t, "warning", msgs[0]["msg"])
+	assert.Equal(t, "WARN", msgs[0]["level"])
+	assert.Equal(t, "error 1", msgs[1]["msg"])
+	assert.Equal(t, "ERROR", msgs[1]["level"])
+	f := msgs[0]["fields"].(map[string]interface{})
+	assert.Equal(t, true, f["grpc_log"], "all lines must be marked as gRPC internal logs")
+}
+
+func TestGrpcLoggerV2_DefaultSeverity(t *testing.T) {
+	os.Unsetenv(grpc_slog.GrpcLogSeverityEnvVar)
+	os.Unsetenv(grpc_slog.GrpcLogVerbosityEnvVar)
+	b := &bytes.Buffer{}
+	l := grpc_slog.NewGrpcLoggerV2(slogjson.Make(b))
+
+	assert.False(t, l.V(1), "the verbosity must be 0 by default")
+
+	l.Info("info")
+	l.Warning("warning")
+	l.Error("error")
+
+	msgs := decodeGrpcLogs(t, b)
+	require.Len(t, msgs, 1, "only errors must be logged when the environment is unset, like grpc's default logger")
+	assert.Equal(t, "error", msgs[0]["msg"])
+}
+
+func TestGrpcLoggerV2_Environment(t *testing.T) {
+	os.Setenv(grpc_slog.GrpcLogSeverityEnvVar, "error")
+	defer os.Unsetenv(grpc_slog.GrpcLogSeverityEnvVar)
+	os.Setenv(grpc_slog.GrpcLogVerbosityEnvVar, "2")
+	defer os.Unsetenv(grpc_slog.GrpcLogVerbosityEnvVar)
+	b := &bytes.Buffer{}
+	l := grpc_slog.NewGrpcLoggerV2(slogjson.Make(b))
+
+	assert.True(t, l.V(2), "the verbosity must be read from the environment")
+	assert.False(t, l.V(3), "the verbosity must be read from the environment")
+
+	l.Warning("warning")
+	l.Error("error")
+
+	msgs := decodeGrpcLogs(t, b)
+	require.Len(t, msgs, 1, "the minimum severity must be read from the environment")
+	assert.Equal(t, "error", msgs[0]["msg"])
+}
+
+func TestGrpcLoggerV2_OptionsOverrideEnvironment(t *testing.T) {
+	os.Setenv(grpc_slog.GrpcLogSeverityEnvVar, "ERROR")
+	defer os.Unsetenv(grpc_slog.GrpcLogSeverityEnvVar)
+	b := &bytes.Buffer{}
+	l := grpc_slog.NewGrpcLoggerV2(slogjson.Make(b),
+		grpc_slog.WithGrpcLogSeverity(slog.LevelInfo),
+		grpc_slog.WithGrpcLogVerbosity(1))
+
+	assert.True(t, l.V(1))
+	assert.False(t, l.V(2))
+
+	l.Info("info")
+
+	msgs := decodeGrpcLogs(t, b)
+	require.Len(t, msgs, 1, "options must take precedence over the environment")
+	assert.Equal(t, "info", msgs[0]["msg"])
+}
+
+func TestReplaceGrpcLoggerV2_Restore(t *testing.T) {
+	if os.Getenv("GRPC_SLOG_TEST_RESTORE") == "" {
+		// The grpclog logger is not safe for concurrent replacement, so the test runs in its own process, where no
+		// gRPC server of the other tests is reading it.
+		cmd := exec.Command(os.Args[0], "-test.run=^TestReplaceGrpcLoggerV2_Restore$")
+		cmd.Env = append(os.Environ(), "GRPC_SLOG_TEST_RESTORE=1")
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+		return
+	}
+	first := &bytes.Buffer{}
+	second := &bytes.Buffer{}
+	restoreFirst := grpc_slog.ReplaceGrpcLoggerV2(slogjson.Make(first), grpc_slog.WithGrpcLogSeverity(slog.LevelInfo))
+	defer restoreFirst()
+	restoreSecond := grpc_slog.ReplaceGrpcLoggerV2(slogjson.Make(second), grpc_slog.WithGrpcLogSeverity(slog.LevelInfo))
+	defer restoreSecond()
+
+	grpclog.Info("second")
+	restoreFirst()
+	grpclog.Info("still second")
+	restoreSecond()
+	grpclog.Info("first")
+
+	msgs := decodeGrpcLogs(t, second)
+	require.Len(t, msgs, 2, "restoring a replaced logger out of order must be a no-op")
+	assert.Equal(t, "second", msgs[0]["msg"])
+	assert.Equal(t, "still second", msgs[1]["msg"])
+	msgs = decodeGrpcLogs(t, first)
+	require.Len(t, msgs, 1, "the previous logger must be reinstated")
+	assert.Equal(t, "first", msgs[0]["msg"])
+
+	restoreFirst()
+	grpclog.Info("default")
+	assert.Empty(t, first.String(), "grpc's default logger must be reinstated once all loggers are restored")
+}
+
+// grpclogInfo mimics grpclog.Info, which calls the logger with the caller of grpclog.Info as the location of the entry.
+func grpclogInfo(l grpclog.LoggerV2, args ...interface{}) {
+	l.Info(args...)
+}
+
+// componentInfo mimics the component loggers of grpc, which add a frame and log through grpclog's depth functions.
+func componentInfo(l grpclog.DepthLoggerV2, args ...interface{}) {
+	grpclogInfoDepth(l, 1, args...)
+}
+
+func grpclogInfoDepth(l grpclog.DepthLoggerV2, depth int, args ...interface{}) {
+	l.InfoDepth(depth, args...)
+}
+
+func TestGrpcLoggerV2_Caller(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := grpc_slog.NewGrpcLoggerV2(slogjson.Make(b), grpc_slog.WithGrpcLogSeverity(slog.LevelInfo))
+	require.Implements(t, (*grpclog.DepthLoggerV2)(nil), l, "the logger must support depth logging")
+	dl := l.(grpclog.DepthLoggerV2)
+
+	pc, file, line, _ := runtime.Caller(0)
+	grpclogInfo(l, "info")
+	componentInfo(dl, "depth", "info")
+
+	msgs := decodeGrpcLogs(t, b)
+	require.Len(t, msgs, 2)
+	fn := runtime.FuncForPC(pc).Name()
+	assert.Equal(t, file+":"+strconv.Itoa(line+1), msgs[0]["caller"], "the caller of grpclog must be logged")
+	assert.Equal(t, fn, msgs[0]["func"], "the caller of grpclog must be logged")
+	assert.Equal(t, file+":"+strconv.Itoa(line+2), msgs[1]["caller"], "the caller of the component logger must be logged")
+	assert.Equal(t, fn, msgs[1]["func"], "the caller of the component logger must be logged")
+	assert.Equal(t, "depth info", msgs[1]["msg"], "depth arguments must be formatted in the manner of fmt.Println")
+}
+
+func TestGrpcLoggerV2_Component(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := grpc_slog.NewGrpcLoggerV2(slogjson.Make(b), grpc_slog.WithGrpcLogSeverity(slog.LevelInfo)).(grpclog.DepthLoggerV2)
+
+	componentInfo(l, "[transport]", "transport: loopyWriter.run returning")
+	componentInfo(l, "[core]", "[Channel #1 SubChannel #4] Subchannel Connectivity change to READY")
+	componentInfo(l, "[core]", "Subchannel(id:3) created")
+	componentInfo(l, "[core]", "Channel(id:1) Subchannel(id:5) replaces Subchannel(id:3) of Channel(id:2)")
+
+	msgs := decodeGrpcLogs(t, b)
+	require.Len(t, msgs, 4)
+
+	f := msgs[0]["fields"].(map[string]interface{})
+	assert.Equal(t, "transport", f["grpc.component"], "the component must be logged")
+	assert.Equal(t, "transport: loopyWriter.run returning", msgs[0]["msg"], "the component must be stripped")
+
+	f = msgs[1]["fields"].(map[string]interface{})
+	assert.Equal(t, "core", f["grpc.component"], "the component must be logged")
+	assert.EqualValues(t, 1, f["grpc.channel_id"], "the channel ID must be logged")
+	assert.EqualValues(t, 4, f["grpc.subchannel_id"], "the sub-channel ID must be logged")
+	assert.Equal(t, "Subchannel Connectivity change to READY", msgs[1]["msg"], "the channel tag must be stripped")
+
+	f = msgs[2]["fields"].(map[string]interface{})
+	assert.EqualValues(t, 3, f["grpc.subchannel_id"], "the referenced sub-channel ID must be logged")
+	assert.NotContains(t, f, "grpc.channel_id")
+	assert.Equal(t, "Subchannel(id:3) created", msgs[2]["msg"], "channel references must be kept")
+
+	f = msgs[3]["fields"].(map[string]interface{})
+	assert.EqualValues(t, 1, f["grpc.channel_id"], "the first referenced channel ID must be logged")
+	assert.EqualValues(t, 5, f["grpc.subchannel_id"], "the first referenced sub-channel ID must be logged")
+}
+
+func TestGrpcLoggerV2_ComponentSeverity(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := grpc_slog.NewGrpcLoggerV2(slogjson.Make(b),
+		grpc_slog.WithGrpcLogSeverity(slog.LevelWarn),
+		grpc_slog.WithGrpcComponentSeverity("transport", slog.LevelError),
+		grpc_slog.WithGrpcComponentSeverity("balancer", slog.LevelInfo)).(grpclog.DepthLoggerV2)
+
+	l.WarningDepth(0, "[transport]", "dropped")
+	l.ErrorDepth(0, "[transport]", "kept")
+	l.InfoDepth(0, "[balancer]", "kept")
+	l.InfoDepth(0, "[core]", "dropped")
+	l.WarningDepth(0, "[core]", "kept")
+
+	msgs := decodeGrpcLogs(t, b)
+	require.Len(t, msgs, 3, "the minimum severity of each component must be used")
+	for _, m := range msgs {
+		assert.Equal(t, "kept", m["msg"])
+	}
+}
+
+func TestGrpcLoggerV2_FatalPanic(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := grpc_slog.NewGrpcLoggerV2(slogjson.Make(b), grpc_slog.WithGrpcFatalHook(grpc_slog.GrpcFatalPanic))
+
+	assert.PanicsWithValue(t, "fatal", func() { l.Fatal("fatal") }, "the hook must be called instead of exiting")
+
+	msgs := decodeGrpcLogs(t, b)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "fatal", msgs[0]["msg"])
+	assert.Equal(t, "CRITICAL", msgs[0]["level"], "fatal logs must be logged at critical level when a hook is set")
+}
+
+func TestGrpcLoggerV2_FatalHook(t *testing.T) {
+	b := &bytes.Buffer{}
+	var hooked []string
+	l := grpc_slog.NewGrpcLoggerV2(slogjson.Make(b), grpc_slog.WithGrpcFatalHook(func(msg string) {
+		hooked = append(hooked, msg)
+	})).(grpclog.DepthLoggerV2)
+
+	l.FatalDepth(0, "[transport]", "fatal", 1)
+
+	assert.Equal(t, []string{"fatal 1"}, hooked, "the hook must be called with the message")
+	msgs := decodeGrpcLogs(t, b)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "CRITICAL", msgs[0]["level"])
+}
+
+func TestGrpcLoggerV2_Deduplication(t *testing.T) {
+	b := grpc_testing.NewMutexReadWriter(&bytes.Buffer{})
+	l := grpc_slog.NewGrpcLoggerV2(slogjson.Make(b),
+		grpc_slog.WithGrpcLogSeverity(slog.LevelWarn),
+		grpc_slog.WithGrpcLogDeduplication(50*time.Millisecond))
+
+	for i := 0; i < 5; i++ {
+		l.Warning("transport: Error while dialing")
+	}
+	l.Error("transport: Error while dialing")
+
+	msgs := decodeGrpcLogs(t, b)
+	require.Len(t, msgs, 2, "repeated logs must be collapsed")
+	assert.Equal(t, "WARN", msgs[0]["level"])
+	assert.Equal(t, "ERROR", msgs[1]["level"], "logs of different severities must not be collapsed")
+
+	require.Eventually(t, func() bool {
+		msgs = append(msgs, decodeGrpcLogs(t, b)...)
+		return len(msgs) > 2
+	}, time.Second, 10*time.Millisecond, "repeated logs must be logged when the window closes")
+	require.Len(t, msgs, 3)
+	assert.Equal(t, "transport: Error while dialing", msgs[2]["msg"])
+	assert.Equal(t, "WARN", msgs[2]["level"])
+	f := msgs[2]["fields"].(map[string]interface{})
+	assert.EqualValues(t, 4, f["repeat_count"], "the number of repetitions must be logged")
+}
+
+func TestGrpcLoggerV2_RateLimit(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := grpc_slog.NewGrpcLoggerV2(slogjson.Make(b),
+		grpc_slog.WithGrpcLogSeverity(slog.LevelWarn),
+		grpc_slog.WithGrpcLogRateLimit(slog.LevelWarn, 2))
+
+	for i := 0; i < 5; i++ {
+		l.Warningf("warning %d", i)
+		l.Errorf("error %d", i)
+	}
+
+	msgs := decodeGrpcLogs(t, b)
+	var warnings int
+	for _, m := range msgs {
+		if m["level"] == "WARN" {
+			warnings++
+		}
+	}
+	assert.Equal(t, 2, warnings, "logs above the rate limit must be dropped")
+	assert.Len(t, msgs, 7, "other severities must not be limited")
+
+	time.Sleep(time.Second)
+	l.Warning("warning")
+
+	msgs = decodeGrpcLogs(t, b)
+	require.Len(t, msgs, 1)
+	f := msgs[0]["fields"].(map[string]interface{})
+	assert.EqualValues(t, 3, f["dropped_count"], "the number of dropped logs must be logged")
+}
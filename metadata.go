@@ -0,0 +1,115 @@
+package grpc_slog
+
+import (
+	"sort"
+	"strings"
+
+	"cdr.dev/slog"
+	"google.golang.org/grpc/metadata"
+)
+
+const redactedValue = "[redacted]"
+
+var (
+	// DefaultRedactedMetadataKeys are the metadata keys whose values are redacted unless overridden by
+	// WithMetadataRedaction. A leading `*` matches any key with the given suffix. Can be overwritten before initialization.
+	DefaultRedactedMetadataKeys = []string{"authorization", "cookie", "*-bin"}
+)
+
+// metadataOptions determines which metadata keys are logged and which of them have their values redacted.
+type metadataOptions struct {
+	enabled bool
+	allow   map[string]bool
+	deny    map[string]bool
+	redact  []string
+}
+
+// WithMetadataAllowlist enables logging of the given metadata keys. Incoming metadata is logged by the server
+// interceptors and outgoing metadata by the client interceptors, as `grpc.request.metadata.<key>` fields.
+func WithMetadataAllowlist(keys ...string) Option {
+	return func(o *options) {
+		o.metadata.enabled = true
+		o.metadata.allow = lowerKeySet(keys)
+	}
+}
+
+// WithMetadataDenylist enables logging of all metadata keys except the given ones.
+func WithMetadataDenylist(keys ...string) Option {
+	return func(o *options) {
+		o.metadata.enabled = true
+		o.metadata.deny = lowerKeySet(keys)
+	}
+}
+
+// WithMetadataRedaction replaces DefaultRedactedMetadataKeys with the given keys. Logged metadata matching any of the
+// keys has its values replaced with `[redacted]`.
+func WithMetadataRedaction(keys ...string) Option {
+	return func(o *options) {
+		o.metadata.redact = keys
+	}
+}
+
+func lowerKeySet(keys []string) map[string]bool {
+	set := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		set[strings.ToLower(k)] = true
+	}
+	return set
+}
+
+// metadataFields converts the metadata into slog fields according to the metadata options.
+func metadataFields(md metadata.MD, o *metadataOptions) []slog.Field {
+	if !o.enabled || len(md) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(md))
+	for k := range md {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var fields []slog.Field
+	for _, k := range keys {
+		if o.allow != nil && !o.allow[k] {
+			continue
+		}
+		if o.deny[k] {
+			continue
+		}
+		var value interface{}
+		switch {
+		case isRedactedKey(k, o.redact):
+			value = redactedValue
+		case len(md[k]) == 1:
+			value = md[k][0]
+		default:
+			value = md[k]
+		}
+		fields = append(fields, slog.F("grpc.request.metadata."+k, value))
+	}
+	return fields
+}
+
+// hasMetadataValue reports whether any of the values of the metadata key equals value.
+func hasMetadataValue(md metadata.MD, key string, value string) bool {
+	for _, v := range md.Get(key) {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func isRedactedKey(key string, redact []string) bool {
+	for _, r := range redact {
+		r = strings.ToLower(r)
+		if strings.HasPrefix(r, "*") {
+			if strings.HasSuffix(key, r[1:]) {
+				return true
+			}
+		} else if key == r {
+			return true
+		}
+	}
+	return false
+}
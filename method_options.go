@@ -0,0 +1,104 @@
+package grpc_slog
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	"cdr.dev/slog"
+)
+
+// methodOverride holds options applied to the calls of the methods matched by match.
+type methodOverride struct {
+	match       func(fullMethod string) bool
+	specificity int
+	opts        []Option
+}
+
+// Specificities of method overrides. More specific overrides are applied later, so they take precedence.
+const (
+	regexpSpecificity = iota
+	serviceSpecificity
+	exactSpecificity
+)
+
+// WithMethodOptions applies the given options to the calls of the methods matching the pattern, on top of the options
+// of the interceptor. The pattern is either a full method name, e.g. `/pkg.Service/Method`, or a service wildcard,
+// e.g. `/pkg.Service/*`. Options for an exact method take precedence over options for its service, which take
+// precedence over options given with WithMethodRegexpOptions.
+func WithMethodOptions(pattern string, opts ...Option) Option {
+	if strings.HasSuffix(pattern, "/*") {
+		service := strings.TrimSuffix(pattern, "*")
+		return withMethodOverride(methodOverride{
+			match:       func(fullMethod string) bool { return strings.HasPrefix(fullMethod, service) },
+			specificity: serviceSpecificity,
+			opts:        opts,
+		})
+	}
+	return withMethodOverride(methodOverride{
+		match:       func(fullMethod string) bool { return fullMethod == pattern },
+		specificity: exactSpecificity,
+		opts:        opts,
+	})
+}
+
+// WithMethodRegexpOptions applies the given options to the calls of the methods whose full method name matches re.
+func WithMethodRegexpOptions(re *regexp.Regexp, opts ...Option) Option {
+	return withMethodOverride(methodOverride{
+		match:       re.MatchString,
+		specificity: regexpSpecificity,
+		opts:        opts,
+	})
+}
+
+func withMethodOverride(mo methodOverride) Option {
+	return func(o *options) {
+		o.methodOverrides = append(o.methodOverrides, mo)
+		o.methodCache = &sync.Map{}
+	}
+}
+
+// WithFields adds the given fields to the logger of every call. Combined with WithMethodOptions, it can be used to
+// add fields to the calls of specific methods.
+func WithFields(fields ...slog.Field) Option {
+	return func(o *options) {
+		o.fields = append(o.fields, fields...)
+	}
+}
+
+// WithPayloadLogging enables or disables the payload interceptors, in addition to their decider. Combined with
+// WithMethodOptions, it can be used to disable payload logging for specific methods.
+func WithPayloadLogging(enabled bool) Option {
+	return func(o *options) {
+		o.payloadDisabled = !enabled
+	}
+}
+
+// forMethod returns the options for calls to fullMethod, with all matching method overrides applied.
+func (o *options) forMethod(fullMethod string) *options {
+	if len(o.methodOverrides) == 0 {
+		return o
+	}
+	if c, ok := o.methodCache.Load(fullMethod); ok {
+		return c.(*options)
+	}
+	c := &options{}
+	*c = *o
+	c.methodOverrides = nil
+	c.methodCache = nil
+	// Copy the slices that options append to, so the base options are not modified.
+	c.fields = append([]slog.Field(nil), o.fields...)
+	c.redactors = append([]FieldRedactor(nil), o.redactors...)
+	for specificity := regexpSpecificity; specificity <= exactSpecificity; specificity++ {
+		for _, mo := range o.methodOverrides {
+			if mo.specificity != specificity || !mo.match(fullMethod) {
+				continue
+			}
+			for _, opt := range mo.opts {
+				opt(c)
+			}
+		}
+	}
+	o.methodCache.Store(fullMethod, c)
+	return c
+}
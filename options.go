@@ -1,26 +1,60 @@
 package grpc_slog
 
 import (
+	"strings"
+	"sync"
 	"time"
 
 	"cdr.dev/slog"
 	grpc_logging "github.com/grpc-ecosystem/go-grpc-middleware/logging"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protojson"
 )
 
 var (
 	defaultOptions = &options{
-		shouldLog:    grpc_logging.DefaultDeciderMethod,
-		codeFunc:     grpc_logging.DefaultErrorToCode,
-		durationFunc: DefaultDurationToField,
+		shouldLog:     grpc_logging.DefaultDeciderMethod,
+		codeFunc:      grpc_logging.DefaultErrorToCode,
+		durationFunc:  DefaultDurationToField,
+		requestIDFunc: DefaultRequestIDGenerator,
 	}
 )
 
 type options struct {
-	levelFunc    CodeToLevel
-	shouldLog    grpc_logging.Decider
-	codeFunc     grpc_logging.ErrorToCode
-	durationFunc DurationToField
+	levelFunc        CodeToLevel
+	shouldLog        grpc_logging.Decider
+	codeFunc         grpc_logging.ErrorToCode
+	durationFunc     DurationToField
+	logStart         bool
+	startLevel       slog.Level
+	metadata         metadataOptions
+	messageStats     bool
+	useContextLogger bool
+	traceContext     bool
+	statusDetails    bool
+
+	sampler          Sampler
+	sampleSlowerThan time.Duration
+
+	slowThreshold time.Duration
+	slowLevel     slog.Level
+
+	debugHeaderKey   string
+	debugHeaderValue string
+
+	requestIDKey    string
+	requestIDFunc   func() string
+	requestIDHeader bool
+
+	fields          []slog.Field
+	payloadDisabled bool
+	methodOverrides []methodOverride
+	methodCache     *sync.Map
+
+	redactors        []FieldRedactor
+	marshalOpts      protojson.MarshalOptions
+	maxPayloadSize   int
+	payloadLimitMode PayloadLimitMode
 }
 
 type Option func(*options)
@@ -28,6 +62,16 @@ type Option func(*options)
 // CodeToLevel function defines the mapping between gRPC return codes and interceptor log level.
 type CodeToLevel func(code codes.Code) slog.Level
 
+// PayloadLimitMode defines how payloads exceeding the maximum size are logged.
+type PayloadLimitMode int
+
+const (
+	// TruncatePayload logs the serialized payload as a string truncated to the maximum size.
+	TruncatePayload PayloadLimitMode = iota
+	// SummarizePayload replaces the payload with a summary containing its type and size.
+	SummarizePayload
+)
+
 // DurationToField function defines how to produce duration fields for logging
 type DurationToField func(duration time.Duration) slog.Field
 
@@ -35,6 +79,7 @@ func evaluateServerOpt(opts []Option) *options {
 	optCopy := &options{}
 	*optCopy = *defaultOptions
 	optCopy.levelFunc = DefaultCodeToLevel
+	optCopy.metadata.redact = DefaultRedactedMetadataKeys
 	for _, o := range opts {
 		o(optCopy)
 	}
@@ -45,6 +90,7 @@ func evaluateClientOpt(opts []Option) *options {
 	optCopy := &options{}
 	*optCopy = *defaultOptions
 	optCopy.levelFunc = DefaultClientCodeToLevel
+	optCopy.metadata.redact = DefaultRedactedMetadataKeys
 	for _, o := range opts {
 		o(optCopy)
 	}
@@ -79,6 +125,82 @@ func WithDurationField(f DurationToField) Option {
 	}
 }
 
+// WithPayloadMarshalOptions customizes how payloads are serialized by the payload interceptors.
+// Multiline output is always disabled, as payloads are embedded in a single log line.
+func WithPayloadMarshalOptions(mo protojson.MarshalOptions) Option {
+	return func(o *options) {
+		mo.Multiline = false
+		mo.Indent = ""
+		o.marshalOpts = mo
+	}
+}
+
+// WithMaxPayloadSize limits the serialized size of each payload logged by the payload interceptors to maxBytes.
+// Larger payloads are logged according to the mode, together with `<key>_truncated: true` and `<key>_size_bytes`
+// fields holding the original size, e.g. `grpc.request.content_truncated`.
+func WithMaxPayloadSize(maxBytes int, mode PayloadLimitMode) Option {
+	return func(o *options) {
+		o.maxPayloadSize = maxBytes
+		o.payloadLimitMode = mode
+	}
+}
+
+// WithSlowCallThreshold escalates the final line of calls taking longer than d to at least the given level, and adds
+// a `grpc.slow: true` field to it. Slow calls are never sampled out.
+func WithSlowCallThreshold(d time.Duration, level slog.Level) Option {
+	return func(o *options) {
+		o.slowThreshold = d
+		o.slowLevel = level
+	}
+}
+
+// isSlowCall reports whether the call duration exceeds the slow call threshold.
+func (o *options) isSlowCall(duration time.Duration) bool {
+	return o.slowThreshold > 0 && duration > o.slowThreshold
+}
+
+// callLevel returns the level of the final line of a call, and any fields describing why it was escalated.
+func (o *options) callLevel(code codes.Code, duration time.Duration) (slog.Level, []slog.Field) {
+	level := o.levelFunc(code)
+	if !o.isSlowCall(duration) {
+		return level, nil
+	}
+	if o.slowLevel > level {
+		level = o.slowLevel
+	}
+	return level, []slog.Field{slog.F("grpc.slow", true)}
+}
+
+// WithDebugHeader enables debug level logging for a single call on the server when the incoming metadata key has the
+// given value, e.g. `WithDebugHeader("x-debug-log", "1")`. All loggers extracted from the context of the call, as well
+// as the interceptor log lines, are affected.
+func WithDebugHeader(key string, value string) Option {
+	return func(o *options) {
+		o.debugHeaderKey = strings.ToLower(key)
+		o.debugHeaderValue = value
+	}
+}
+
+// WithContextLogger makes the client interceptors log through the call-scoped logger of the context, as returned by
+// `ctxslog.Extract`, when there is one. This adds the fields of an inbound call, such as its tags, to the logs of the
+// outbound calls made while handling it. The fields of the inbound call that the client fields replace, such as
+// `span.kind` and `grpc.method`, are dropped when equal and logged under the `grpc.parent.` prefix otherwise, as done
+// by `ctxslog.ExtractWith`.
+func WithContextLogger() Option {
+	return func(o *options) {
+		o.useContextLogger = true
+	}
+}
+
+// WithStartLog enables logging of a "started call" line at the given level when a call begins.
+// The decider is consulted with a nil error to determine whether the line should be logged.
+func WithStartLog(level slog.Level) Option {
+	return func(o *options) {
+		o.logStart = true
+		o.startLevel = level
+	}
+}
+
 // DefaultCodeToLevel is the default implementation of gRPC return codes and interceptor log level for server side.
 func DefaultCodeToLevel(code codes.Code) slog.Level {
 	switch code {
@@ -1,39 +1,37 @@
 package grpc_slog
 
 import (
-	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"unicode/utf8"
 
 	"cdr.dev/slog"
-	"github.com/golang/protobuf/jsonpb"
-	"github.com/golang/protobuf/proto"
+	protov1 "github.com/golang/protobuf/proto"
 	grpc_logging "github.com/grpc-ecosystem/go-grpc-middleware/logging"
 	"github.com/hassieswift621/slog-grpc-mw/ctxslog"
 	"google.golang.org/grpc"
-)
-
-var (
-	// JsonPbMarshaller is the marshaller used for serializing protobuf messages.
-	// If needed, this variable can be reassigned with a different marshaller with the same Marshal() signature.
-	JsonPbMarshaller grpc_logging.JsonPbMarshaler = &jsonpb.Marshaler{}
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 )
 
 // PayloadUnaryServerInterceptor returns a new unary server interceptors that logs the payloads of requests.
 //
 // This *only* works when placed *after* the `grpc_slog.UnaryServerInterceptor`. However, the logging can be done to a
 // separate instance of the logger.
-func PayloadUnaryServerInterceptor(logger slog.Logger, decider grpc_logging.ServerPayloadLoggingDecider) grpc.UnaryServerInterceptor {
+func PayloadUnaryServerInterceptor(logger slog.Logger, decider grpc_logging.ServerPayloadLoggingDecider, opts ...Option) grpc.UnaryServerInterceptor {
+	o := evaluateServerOpt(opts)
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		if !decider(ctx, info.FullMethod, info.Server) {
+		o := o.forMethod(info.FullMethod)
+		if o.payloadDisabled || !decider(ctx, info.FullMethod, info.Server) {
 			return handler(ctx, req)
 		}
 		// Use the provided slog.Logger for logging but use the fields from context.
-		logEntry := logger.With(append(serverCallFields(info.FullMethod), ctxslog.TagsToFields(ctx)...)...)
-		logProtoMessageAsJson(ctx, logEntry, req, "grpc.request.content", "server request payload logged as grpc.request.content field")
+		logEntry := logger.With(append(serverCallFields(info.FullMethod), ctxslog.TagsToFields(ctx)...)...).With(o.fields...)
+		logProtoMessageAsJson(ctx, o, logEntry, req, "grpc.request.content", "server request payload logged as grpc.request.content field")
 		resp, err := handler(ctx, req)
 		if err == nil {
-			logProtoMessageAsJson(ctx, logEntry, resp, "grpc.response.content", "server response payload logged as grpc.response.content field")
+			logProtoMessageAsJson(ctx, o, logEntry, resp, "grpc.response.content", "server response payload logged as grpc.response.content field")
 		}
 		return resp, err
 	}
@@ -43,42 +41,48 @@ func PayloadUnaryServerInterceptor(logger slog.Logger, decider grpc_logging.Serv
 //
 // This *only* works when placed *after* the `grpc_slog.StreamServerInterceptor`. However, the logging can be done to a
 // separate instance of the logger.
-func PayloadStreamServerInterceptor(logger slog.Logger, decider grpc_logging.ServerPayloadLoggingDecider) grpc.StreamServerInterceptor {
+func PayloadStreamServerInterceptor(logger slog.Logger, decider grpc_logging.ServerPayloadLoggingDecider, opts ...Option) grpc.StreamServerInterceptor {
+	o := evaluateServerOpt(opts)
 	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-		if !decider(stream.Context(), info.FullMethod, srv) {
+		o := o.forMethod(info.FullMethod)
+		if o.payloadDisabled || !decider(stream.Context(), info.FullMethod, srv) {
 			return handler(srv, stream)
 		}
-		logEntry := logger.With(append(serverCallFields(info.FullMethod), ctxslog.TagsToFields(stream.Context())...)...)
-		newStream := &loggingServerStream{ServerStream: stream, logger: logEntry}
+		logEntry := logger.With(append(serverCallFields(info.FullMethod), ctxslog.TagsToFields(stream.Context())...)...).With(o.fields...)
+		newStream := &loggingServerStream{ServerStream: stream, logger: logEntry, opts: o}
 		return handler(srv, newStream)
 	}
 }
 
 // PayloadUnaryClientInterceptor returns a new unary client interceptor that logs the payloads of requests and responses.
-func PayloadUnaryClientInterceptor(logger slog.Logger, decider grpc_logging.ClientPayloadLoggingDecider) grpc.UnaryClientInterceptor {
+func PayloadUnaryClientInterceptor(logger slog.Logger, decider grpc_logging.ClientPayloadLoggingDecider, opts ...Option) grpc.UnaryClientInterceptor {
+	o := evaluateClientOpt(opts)
 	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
-		if !decider(ctx, method) {
+		o := o.forMethod(method)
+		if o.payloadDisabled || !decider(ctx, method) {
 			return invoker(ctx, method, req, reply, cc, opts...)
 		}
-		logEntry := logger.With(newClientLoggerFields(ctx, method)...)
-		logProtoMessageAsJson(ctx, logEntry, req, "grpc.request.content", "client request payload logged as grpc.request.content")
+		logEntry := logger.With(append(newClientLoggerFields(ctx, method), o.fields...)...)
+		logProtoMessageAsJson(ctx, o, logEntry, req, "grpc.request.content", "client request payload logged as grpc.request.content")
 		err := invoker(ctx, method, req, reply, cc, opts...)
 		if err == nil {
-			logProtoMessageAsJson(ctx, logEntry, reply, "grpc.response.content", "client response payload logged as grpc.response.content")
+			logProtoMessageAsJson(ctx, o, logEntry, reply, "grpc.response.content", "client response payload logged as grpc.response.content")
 		}
 		return err
 	}
 }
 
 // PayloadStreamClientInterceptor returns a new streaming client interceptor that logs the payloads of requests and responses.
-func PayloadStreamClientInterceptor(logger slog.Logger, decider grpc_logging.ClientPayloadLoggingDecider) grpc.StreamClientInterceptor {
+func PayloadStreamClientInterceptor(logger slog.Logger, decider grpc_logging.ClientPayloadLoggingDecider, opts ...Option) grpc.StreamClientInterceptor {
+	o := evaluateClientOpt(opts)
 	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
-		if !decider(ctx, method) {
+		o := o.forMethod(method)
+		if o.payloadDisabled || !decider(ctx, method) {
 			return streamer(ctx, desc, cc, method, opts...)
 		}
-		logEntry := logger.With(newClientLoggerFields(ctx, method)...)
+		logEntry := logger.With(append(newClientLoggerFields(ctx, method), o.fields...)...)
 		clientStream, err := streamer(ctx, desc, cc, method, opts...)
-		newStream := &loggingClientStream{ClientStream: clientStream, logger: logEntry}
+		newStream := &loggingClientStream{ClientStream: clientStream, logger: logEntry, opts: o}
 		return newStream, err
 	}
 }
@@ -86,12 +90,13 @@ func PayloadStreamClientInterceptor(logger slog.Logger, decider grpc_logging.Cli
 type loggingClientStream struct {
 	grpc.ClientStream
 	logger slog.Logger
+	opts   *options
 }
 
 func (l *loggingClientStream) SendMsg(m interface{}) error {
 	err := l.ClientStream.SendMsg(m)
 	if err == nil {
-		logProtoMessageAsJson(l.Context(), l.logger, m, "grpc.request.content", "server request payload logged as grpc.request.content field")
+		logProtoMessageAsJson(l.Context(), l.opts, l.logger, m, "grpc.request.content", "server request payload logged as grpc.request.content field")
 	}
 	return err
 }
@@ -99,7 +104,7 @@ func (l *loggingClientStream) SendMsg(m interface{}) error {
 func (l *loggingClientStream) RecvMsg(m interface{}) error {
 	err := l.ClientStream.RecvMsg(m)
 	if err == nil {
-		logProtoMessageAsJson(l.Context(), l.logger, m, "grpc.response.content", "server response payload logged as grpc.response.content field")
+		logProtoMessageAsJson(l.Context(), l.opts, l.logger, m, "grpc.response.content", "server response payload logged as grpc.response.content field")
 	}
 	return err
 }
@@ -107,12 +112,13 @@ func (l *loggingClientStream) RecvMsg(m interface{}) error {
 type loggingServerStream struct {
 	grpc.ServerStream
 	logger slog.Logger
+	opts   *options
 }
 
 func (l *loggingServerStream) SendMsg(m interface{}) error {
 	err := l.ServerStream.SendMsg(m)
 	if err == nil {
-		logProtoMessageAsJson(l.Context(), l.logger, m, "grpc.response.content", "server response payload logged as grpc.response.content field")
+		logProtoMessageAsJson(l.Context(), l.opts, l.logger, m, "grpc.response.content", "server response payload logged as grpc.response.content field")
 	}
 	return err
 }
@@ -120,25 +126,73 @@ func (l *loggingServerStream) SendMsg(m interface{}) error {
 func (l *loggingServerStream) RecvMsg(m interface{}) error {
 	err := l.ServerStream.RecvMsg(m)
 	if err == nil {
-		logProtoMessageAsJson(l.Context(), l.logger, m, "grpc.request.content", "server request payload logged as grpc.request.content field")
+		logProtoMessageAsJson(l.Context(), l.opts, l.logger, m, "grpc.request.content", "server request payload logged as grpc.request.content field")
 	}
 	return err
 }
 
-func logProtoMessageAsJson(ctx context.Context, logger slog.Logger, pbMsg interface{}, key string, msg string) {
-	if p, ok := pbMsg.(proto.Message); ok {
-		logger.Info(ctx, msg, slog.F(key, &jsonpbObjectMarshaler{pb: p}))
+func logProtoMessageAsJson(ctx context.Context, o *options, logger slog.Logger, pbMsg interface{}, key string, msg string) {
+	var p proto.Message
+	switch m := pbMsg.(type) {
+	case proto.Message:
+		p = m
+	case protov1.Message:
+		p = protov1.MessageV2(m)
+	default:
+		return
+	}
+	p = redactProtoMessage(p, o.redactors, o.marshalOpts.Resolver)
+	logger.Info(ctx, msg, payloadFields(o, p, key)...)
+}
+
+// payloadFields returns the fields for logging the message under the key, applying the payload size limit.
+func payloadFields(o *options, p proto.Message, key string) []slog.Field {
+	marshaler := &protojsonObjectMarshaler{pb: p, opts: o.marshalOpts}
+	if o.maxPayloadSize <= 0 {
+		return []slog.Field{slog.F(key, marshaler)}
+	}
+	b, err := marshaler.MarshalJSON()
+	if err != nil {
+		return []slog.Field{slog.F(key, marshaler)}
+	}
+	if len(b) <= o.maxPayloadSize {
+		// Log the serialized payload as is, so it is not serialized a second time by the sink.
+		return []slog.Field{slog.F(key, json.RawMessage(b))}
+	}
+	var content slog.Field
+	switch o.payloadLimitMode {
+	case SummarizePayload:
+		content = slog.F(key, slog.M(
+			slog.F("@type", string(p.ProtoReflect().Descriptor().FullName())),
+			slog.F("size_bytes", len(b)),
+		))
+	default:
+		content = slog.F(key, truncateUTF8(b, o.maxPayloadSize))
+	}
+	return []slog.Field{
+		content,
+		slog.F(key+"_truncated", true),
+		slog.F(key+"_size_bytes", len(b)),
+	}
+}
+
+// truncateUTF8 truncates b to at most n bytes without splitting a UTF-8 encoded rune.
+func truncateUTF8(b []byte, n int) string {
+	for n > 0 && !utf8.RuneStart(b[n]) {
+		n--
 	}
+	return string(b[:n])
 }
 
-type jsonpbObjectMarshaler struct {
-	pb proto.Message
+type protojsonObjectMarshaler struct {
+	pb   proto.Message
+	opts protojson.MarshalOptions
 }
 
-func (j *jsonpbObjectMarshaler) MarshalJSON() ([]byte, error) {
-	b := &bytes.Buffer{}
-	if err := JsonPbMarshaller.Marshal(b, j.pb); err != nil {
-		return nil, fmt.Errorf("jsonpb serializer failed: %verbosity", err)
+func (j *protojsonObjectMarshaler) MarshalJSON() ([]byte, error) {
+	b, err := j.opts.Marshal(j.pb)
+	if err != nil {
+		return nil, fmt.Errorf("protojson serializer failed: %v", err)
 	}
-	return b.Bytes(), nil
+	return b, nil
 }
@@ -18,6 +18,8 @@ import (
 	grpc_slog "github.com/hassieswift621/slog-grpc-mw"
 	"github.com/stretchr/testify/suite"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
 func TestSlogPayloadSuite(t *testing.T) {
@@ -142,3 +144,240 @@ func (s *slogPayloadSuite) TestPingStream_LogsAllRequestsAndResponses() {
 		assert.True(s.T(), content, "all messages must contain payloads")
 	}
 }
+
+func TestSlogPayloadRedactionSuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skipf("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+
+	alwaysLoggingDeciderServer := func(ctx context.Context, fullMethodName string, servingObject interface{}) bool { return true }
+	alwaysLoggingDeciderClient := func(ctx context.Context, fullMethodName string) bool { return true }
+	opts := []grpc_slog.Option{
+		grpc_slog.WithRedactedFields("mwitkow.testproto.PingRequest.value"),
+		grpc_slog.WithFieldRedactor(func(fd protoreflect.FieldDescriptor) bool {
+			return fd.Name() == "counter"
+		}),
+	}
+
+	b := newBaseSlogSuite(t)
+	b.InterceptorTestSuite.ClientOpts = []grpc.DialOption{
+		grpc.WithUnaryInterceptor(grpc_slog.PayloadUnaryClientInterceptor(b.log, alwaysLoggingDeciderClient, opts...)),
+		grpc.WithStreamInterceptor(grpc_slog.PayloadStreamClientInterceptor(b.log, alwaysLoggingDeciderClient, opts...)),
+	}
+	noOpSlog := slogjson.Make(ioutil.Discard)
+	b.InterceptorTestSuite.ServerOpts = []grpc.ServerOption{
+		grpc_middleware.WithStreamServerChain(
+			grpc_ctxtags.StreamServerInterceptor(),
+			grpc_slog.StreamServerInterceptor(noOpSlog),
+			grpc_slog.PayloadStreamServerInterceptor(b.log, alwaysLoggingDeciderServer, opts...)),
+		grpc_middleware.WithUnaryServerChain(
+			grpc_ctxtags.UnaryServerInterceptor(),
+			grpc_slog.UnaryServerInterceptor(noOpSlog),
+			grpc_slog.PayloadUnaryServerInterceptor(b.log, alwaysLoggingDeciderServer, opts...)),
+	}
+	suite.Run(t, &slogPayloadRedactionSuite{&slogPayloadSuite{b}})
+}
+
+type slogPayloadRedactionSuite struct {
+	*slogPayloadSuite
+}
+
+func (s *slogPayloadRedactionSuite) TestPing_RedactsFields() {
+	ping := &pb_testproto.PingRequest{Value: "secret", SleepTimeMs: 9999}
+	_, err := s.Client.Ping(s.SimpleCtx(), ping)
+
+	require.NoError(s.T(), err, "there must be not be an error on a successful call")
+	serverMsgs, clientMsgs := s.getServerAndClientMessages(2, 2)
+
+	for _, req := range []map[string]interface{}{serverMsgs[0], clientMsgs[0]} {
+		// Get slog fields.
+		f := req["fields"].(map[string]interface{})
+
+		content := f["grpc.request.content"].(map[string]interface{})
+		assert.Equal(s.T(), "[redacted]", content["value"], "fields matched by name must be redacted")
+		assert.EqualValues(s.T(), 9999, content["sleepTimeMs"], "other fields must be logged as is")
+	}
+	for _, resp := range []map[string]interface{}{serverMsgs[1], clientMsgs[1]} {
+		// Get slog fields.
+		f := resp["fields"].(map[string]interface{})
+
+		content := f["grpc.response.content"].(map[string]interface{})
+		assert.Equal(s.T(), "secret", content["Value"], "fields of other messages must not be redacted")
+		assert.NotContains(s.T(), content, "counter", "fields matched by the redactor must be cleared")
+	}
+	assert.Equal(s.T(), "secret", ping.Value, "the original message must not be modified")
+}
+
+func TestSlogPayloadMarshalOptionsSuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skipf("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+
+	alwaysLoggingDeciderServer := func(ctx context.Context, fullMethodName string, servingObject interface{}) bool { return true }
+	alwaysLoggingDeciderClient := func(ctx context.Context, fullMethodName string) bool { return true }
+	opts := []grpc_slog.Option{
+		grpc_slog.WithPayloadMarshalOptions(protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true, Multiline: true}),
+	}
+
+	b := newBaseSlogSuite(t)
+	b.InterceptorTestSuite.ClientOpts = []grpc.DialOption{
+		grpc.WithUnaryInterceptor(grpc_slog.PayloadUnaryClientInterceptor(b.log, alwaysLoggingDeciderClient, opts...)),
+		grpc.WithStreamInterceptor(grpc_slog.PayloadStreamClientInterceptor(b.log, alwaysLoggingDeciderClient, opts...)),
+	}
+	noOpSlog := slogjson.Make(ioutil.Discard)
+	b.InterceptorTestSuite.ServerOpts = []grpc.ServerOption{
+		grpc_middleware.WithStreamServerChain(
+			grpc_ctxtags.StreamServerInterceptor(),
+			grpc_slog.StreamServerInterceptor(noOpSlog),
+			grpc_slog.PayloadStreamServerInterceptor(b.log, alwaysLoggingDeciderServer, opts...)),
+		grpc_middleware.WithUnaryServerChain(
+			grpc_ctxtags.UnaryServerInterceptor(),
+			grpc_slog.UnaryServerInterceptor(noOpSlog),
+			grpc_slog.PayloadUnaryServerInterceptor(b.log, alwaysLoggingDeciderServer, opts...)),
+	}
+	suite.Run(t, &slogPayloadMarshalOptionsSuite{&slogPayloadSuite{b}})
+}
+
+type slogPayloadMarshalOptionsSuite struct {
+	*slogPayloadSuite
+}
+
+func (s *slogPayloadMarshalOptionsSuite) TestPing_UsesMarshalOptions() {
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing)
+
+	require.NoError(s.T(), err, "there must be not be an error on a successful call")
+	serverMsgs, clientMsgs := s.getServerAndClientMessages(2, 2)
+
+	for _, req := range []map[string]interface{}{serverMsgs[0], clientMsgs[0]} {
+		// Get slog fields.
+		f := req["fields"].(map[string]interface{})
+
+		content := f["grpc.request.content"].(map[string]interface{})
+		assert.EqualValues(s.T(), 9999, content["sleep_time_ms"], "proto names must be used")
+		assert.EqualValues(s.T(), 0, content["error_code_returned"], "unpopulated fields must be emitted")
+	}
+}
+
+func TestSlogPayloadSizeLimitSuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skipf("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+
+	alwaysLoggingDeciderServer := func(ctx context.Context, fullMethodName string, servingObject interface{}) bool { return true }
+	alwaysLoggingDeciderClient := func(ctx context.Context, fullMethodName string) bool { return true }
+	clientOpts := []grpc_slog.Option{grpc_slog.WithMaxPayloadSize(20, grpc_slog.TruncatePayload)}
+	serverOpts := []grpc_slog.Option{grpc_slog.WithMaxPayloadSize(20, grpc_slog.SummarizePayload)}
+
+	b := newBaseSlogSuite(t)
+	b.InterceptorTestSuite.ClientOpts = []grpc.DialOption{
+		grpc.WithUnaryInterceptor(grpc_slog.PayloadUnaryClientInterceptor(b.log, alwaysLoggingDeciderClient, clientOpts...)),
+		grpc.WithStreamInterceptor(grpc_slog.PayloadStreamClientInterceptor(b.log, alwaysLoggingDeciderClient, clientOpts...)),
+	}
+	noOpSlog := slogjson.Make(ioutil.Discard)
+	b.InterceptorTestSuite.ServerOpts = []grpc.ServerOption{
+		grpc_middleware.WithStreamServerChain(
+			grpc_ctxtags.StreamServerInterceptor(),
+			grpc_slog.StreamServerInterceptor(noOpSlog),
+			grpc_slog.PayloadStreamServerInterceptor(b.log, alwaysLoggingDeciderServer, serverOpts...)),
+		grpc_middleware.WithUnaryServerChain(
+			grpc_ctxtags.UnaryServerInterceptor(),
+			grpc_slog.UnaryServerInterceptor(noOpSlog),
+			grpc_slog.PayloadUnaryServerInterceptor(b.log, alwaysLoggingDeciderServer, serverOpts...)),
+	}
+	suite.Run(t, &slogPayloadSizeLimitSuite{&slogPayloadSuite{b}})
+}
+
+type slogPayloadSizeLimitSuite struct {
+	*slogPayloadSuite
+}
+
+func (s *slogPayloadSizeLimitSuite) TestPing_LimitsPayloadSize() {
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing)
+
+	require.NoError(s.T(), err, "there must be not be an error on a successful call")
+	serverMsgs, clientMsgs := s.getServerAndClientMessages(2, 2)
+
+	// Get slog fields.
+	clientReq := clientMsgs[0]["fields"].(map[string]interface{})
+	serverReq := serverMsgs[0]["fields"].(map[string]interface{})
+
+	require.IsType(s.T(), "", clientReq["grpc.request.content"], "truncated payloads must be logged as a string")
+	assert.Len(s.T(), clientReq["grpc.request.content"], 20, "truncated payloads must not exceed the maximum size")
+	assert.Equal(s.T(), true, clientReq["grpc.request.content_truncated"], "truncated payloads must be marked")
+	assert.Greater(s.T(), clientReq["grpc.request.content_size_bytes"], float64(20), "the original size must be logged")
+
+	summary := serverReq["grpc.request.content"].(map[string]interface{})
+	assert.Equal(s.T(), "mwitkow.testproto.PingRequest", summary["@type"], "summaries must contain the message type")
+	assert.Equal(s.T(), serverReq["grpc.request.content_size_bytes"], summary["size_bytes"], "summaries must contain the size")
+	assert.Equal(s.T(), true, serverReq["grpc.request.content_truncated"], "summarized payloads must be marked")
+}
+
+func TestSlogPayloadMethodOptionsSuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skipf("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+
+	alwaysLoggingDeciderServer := func(ctx context.Context, fullMethodName string, servingObject interface{}) bool { return true }
+	alwaysLoggingDeciderClient := func(ctx context.Context, fullMethodName string) bool { return true }
+	opts := []grpc_slog.Option{
+		grpc_slog.WithMethodOptions("/mwitkow.testproto.TestService/PingList", grpc_slog.WithPayloadLogging(false)),
+	}
+
+	b := newBaseSlogSuite(t)
+	b.InterceptorTestSuite.ClientOpts = []grpc.DialOption{
+		grpc.WithUnaryInterceptor(grpc_slog.PayloadUnaryClientInterceptor(b.log, alwaysLoggingDeciderClient, opts...)),
+		grpc.WithStreamInterceptor(grpc_slog.PayloadStreamClientInterceptor(b.log, alwaysLoggingDeciderClient, opts...)),
+	}
+	noOpSlog := slogjson.Make(ioutil.Discard)
+	b.InterceptorTestSuite.ServerOpts = []grpc.ServerOption{
+		grpc_middleware.WithStreamServerChain(
+			grpc_ctxtags.StreamServerInterceptor(),
+			grpc_slog.StreamServerInterceptor(noOpSlog),
+			grpc_slog.PayloadStreamServerInterceptor(b.log, alwaysLoggingDeciderServer, opts...)),
+		grpc_middleware.WithUnaryServerChain(
+			grpc_ctxtags.UnaryServerInterceptor(),
+			grpc_slog.UnaryServerInterceptor(noOpSlog),
+			grpc_slog.PayloadUnaryServerInterceptor(b.log, alwaysLoggingDeciderServer, opts...)),
+	}
+	suite.Run(t, &slogPayloadMethodOptionsSuite{&slogPayloadSuite{b}})
+}
+
+type slogPayloadMethodOptionsSuite struct {
+	*slogPayloadSuite
+}
+
+func (s *slogPayloadMethodOptionsSuite) TestPing_LogsPayloads() {
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing)
+
+	require.NoError(s.T(), err, "there must be not be an error on a successful call")
+	s.getServerAndClientMessages(2, 2)
+}
+
+func (s *slogPayloadMethodOptionsSuite) TestPingList_DoesNotLogPayloads() {
+	stream, err := s.Client.PingList(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "should not fail on establishing the stream")
+	for {
+		_, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(s.T(), err, "reading stream should not fail")
+	}
+	s.getServerAndClientMessages(0, 0)
+}
+
+func (s *slogPayloadSizeLimitSuite) TestPingEmpty_KeepsSmallPayloads() {
+	_, err := s.Client.PingEmpty(s.SimpleCtx(), &pb_testproto.Empty{})
+
+	require.NoError(s.T(), err, "there must be not be an error on a successful call")
+	serverMsgs, clientMsgs := s.getServerAndClientMessages(2, 2)
+	for _, m := range []map[string]interface{}{serverMsgs[0], clientMsgs[0]} {
+		f := m["fields"].(map[string]interface{})
+		assert.Equal(s.T(), map[string]interface{}{}, f["grpc.request.content"], "small payloads must be logged as objects")
+		assert.NotContains(s.T(), f, "grpc.request.content_truncated", "small payloads must not be marked")
+	}
+}
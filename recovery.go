@@ -0,0 +1,53 @@
+package grpc_slog
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"cdr.dev/slog"
+	"github.com/hassieswift621/slog-grpc-mw/ctxslog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RecoveryUnaryServerInterceptor returns a new unary server interceptor that recovers from panics in handlers.
+// The panic is logged at the critical level through the call-scoped logger and converted into a codes.Internal error.
+//
+// This *only* works when placed *after* the `grpc_slog.UnaryServerInterceptor`, which then logs the completion of the
+// call with the codes.Internal code.
+func RecoveryUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (_ interface{}, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = recoverFrom(ctx, p)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// RecoveryStreamServerInterceptor returns a new streaming server interceptor that recovers from panics in handlers.
+// The panic is logged at the critical level through the call-scoped logger and converted into a codes.Internal error.
+//
+// This *only* works when placed *after* the `grpc_slog.StreamServerInterceptor`, which then logs the completion of the
+// call with the codes.Internal code.
+func RecoveryStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = recoverFrom(stream.Context(), p)
+			}
+		}()
+		return handler(srv, stream)
+	}
+}
+
+func recoverFrom(ctx context.Context, p interface{}) error {
+	ctxslog.Extract(ctx).Critical(ctx, "recovered from panic in handler",
+		slog.F("panic", fmt.Sprint(p)),
+		slog.F("stack", string(debug.Stack())),
+	)
+	return status.Errorf(codes.Internal, "panic: %v", p)
+}
@@ -0,0 +1,92 @@
+package grpc_slog_test
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	grpc_ctxtags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	grpc_testing "github.com/grpc-ecosystem/go-grpc-middleware/testing"
+	pb_testproto "github.com/grpc-ecosystem/go-grpc-middleware/testing/testproto"
+	grpc_slog "github.com/hassieswift621/slog-grpc-mw"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type panickingPingService struct {
+	pb_testproto.TestServiceServer
+}
+
+func (s *panickingPingService) Ping(ctx context.Context, ping *pb_testproto.PingRequest) (*pb_testproto.PingResponse, error) {
+	panic("very bad thing happened")
+}
+
+func (s *panickingPingService) PingList(ping *pb_testproto.PingRequest, stream pb_testproto.TestService_PingListServer) error {
+	panic("very bad thing happened")
+}
+
+func TestSlogRecoverySuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skip("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+	b := newBaseSlogSuite(t)
+	b.InterceptorTestSuite.TestService = &panickingPingService{&grpc_testing.TestPingService{T: t}}
+	b.InterceptorTestSuite.ServerOpts = []grpc.ServerOption{
+		grpc_middleware.WithStreamServerChain(
+			grpc_ctxtags.StreamServerInterceptor(),
+			grpc_slog.StreamServerInterceptor(b.log),
+			grpc_slog.RecoveryStreamServerInterceptor()),
+		grpc_middleware.WithUnaryServerChain(
+			grpc_ctxtags.UnaryServerInterceptor(),
+			grpc_slog.UnaryServerInterceptor(b.log),
+			grpc_slog.RecoveryUnaryServerInterceptor()),
+	}
+	suite.Run(t, &slogRecoverySuite{b})
+}
+
+type slogRecoverySuite struct {
+	*slogBaseSuite
+}
+
+func (s *slogRecoverySuite) TestPing_RecoversFromPanic() {
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing)
+	require.Error(s.T(), err, "a panicking call must return an error")
+	assert.Equal(s.T(), codes.Internal, status.Code(err), "a panicking call must return Internal")
+
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 2, "two log statements should be logged")
+	for _, m := range msgs {
+		// Get slog fields.
+		f := m["fields"].(map[string]interface{})
+
+		assert.Equal(s.T(), f["grpc.method"], "Ping", "all lines must contain the call fields")
+	}
+
+	// Get slog fields.
+	f := msgs[0]["fields"].(map[string]interface{})
+
+	assert.Equal(s.T(), msgs[0]["level"], "CRITICAL", "the panic must be logged at critical level")
+	assert.Equal(s.T(), f["panic"], "very bad thing happened", "the panic value must be logged")
+	assert.Contains(s.T(), f["stack"], "recovery_test.go", "the stack trace must be logged")
+
+	assert.Equal(s.T(), msgs[1]["msg"], "finished unary call with code Internal", "the final line must be logged with Internal")
+}
+
+func (s *slogRecoverySuite) TestPingList_RecoversFromPanic() {
+	stream, err := s.Client.PingList(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "should not fail on establishing the stream")
+	_, err = stream.Recv()
+	assert.Equal(s.T(), codes.Internal, status.Code(err), "a panicking call must return Internal")
+
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 2, "two log statements should be logged")
+	assert.Equal(s.T(), msgs[0]["level"], "CRITICAL", "the panic must be logged at critical level")
+	assert.Equal(s.T(), msgs[1]["msg"], "finished streaming call with code Internal", "the final line must be logged with Internal")
+}
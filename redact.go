@@ -0,0 +1,168 @@
+package grpc_slog
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const redactedFieldValue = "[redacted]"
+
+// FieldRedactor decides whether the value of a protobuf field should be redacted from logged payloads.
+type FieldRedactor func(fd protoreflect.FieldDescriptor) bool
+
+// WithRedactedFields redacts the fields with the given full names (e.g. `mypkg.LoginRequest.password`) from logged
+// payloads. String fields are replaced with `[redacted]`, all other fields are cleared.
+func WithRedactedFields(fullNames ...string) Option {
+	names := make(map[protoreflect.FullName]bool, len(fullNames))
+	for _, n := range fullNames {
+		names[protoreflect.FullName(n)] = true
+	}
+	return WithFieldRedactor(func(fd protoreflect.FieldDescriptor) bool {
+		return names[fd.FullName()]
+	})
+}
+
+// WithRedactionExtension redacts fields annotated with the given boolean field option, e.g. `[(sensitive) = true]`,
+// from logged payloads.
+func WithRedactionExtension(xt protoreflect.ExtensionType) Option {
+	return WithFieldRedactor(func(fd protoreflect.FieldDescriptor) bool {
+		opts, ok := fd.Options().(*descriptorpb.FieldOptions)
+		if !ok || opts == nil || !proto.HasExtension(opts, xt) {
+			return false
+		}
+		v, ok := proto.GetExtension(opts, xt).(bool)
+		return ok && v
+	})
+}
+
+// WithFieldRedactor redacts the fields for which the given function returns true from logged payloads.
+func WithFieldRedactor(f FieldRedactor) Option {
+	return func(o *options) {
+		o.redactors = append(o.redactors, f)
+	}
+}
+
+// redactProtoMessage returns a copy of the message with all fields matched by the redactors redacted.
+// The message is returned as is when there are no redactors.
+//
+// The contents of google.protobuf.Any values are redacted as well when their type is known to the resolver, which
+// defaults to the global registry like protojson does. Any values of unknown types are left as is, as protojson fails
+// to marshal them.
+func redactProtoMessage(m proto.Message, redactors []FieldRedactor, resolver protoregistry.MessageTypeResolver) proto.Message {
+	if len(redactors) == 0 {
+		return m
+	}
+	if resolver == nil {
+		resolver = protoregistry.GlobalTypes
+	}
+	c := proto.Clone(m)
+	redactMessage(c.ProtoReflect(), redactors, resolver)
+	return c
+}
+
+func redactMessage(m protoreflect.Message, redactors []FieldRedactor, resolver protoregistry.MessageTypeResolver) {
+	if m.Descriptor().FullName() == "google.protobuf.Any" {
+		redactAny(m, redactors, resolver)
+		return
+	}
+	// Collect the populated fields first, as the message must not be mutated while ranging over it.
+	var fds []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		fds = append(fds, fd)
+		return true
+	})
+	for _, fd := range fds {
+		if shouldRedact(fd, redactors) {
+			redactField(m, fd)
+			continue
+		}
+		switch {
+		case fd.IsMap():
+			if fd.MapValue().Message() == nil {
+				continue
+			}
+			m.Get(fd).Map().Range(func(_ protoreflect.MapKey, v protoreflect.Value) bool {
+				redactMessage(v.Message(), redactors, resolver)
+				return true
+			})
+		case fd.IsList():
+			if fd.Message() == nil {
+				continue
+			}
+			l := m.Get(fd).List()
+			for i := 0; i < l.Len(); i++ {
+				redactMessage(l.Get(i).Message(), redactors, resolver)
+			}
+		case fd.Message() != nil:
+			redactMessage(m.Get(fd).Message(), redactors, resolver)
+		}
+	}
+}
+
+// redactAny redacts the message packed in a google.protobuf.Any value.
+func redactAny(m protoreflect.Message, redactors []FieldRedactor, resolver protoregistry.MessageTypeResolver) {
+	fields := m.Descriptor().Fields()
+	typeURL, value := fields.ByName("type_url"), fields.ByName("value")
+	if typeURL == nil || value == nil {
+		return
+	}
+	mt, err := resolver.FindMessageByURL(m.Get(typeURL).String())
+	if err != nil {
+		return
+	}
+	packed := mt.New()
+	if err := proto.Unmarshal(m.Get(value).Bytes(), packed.Interface()); err != nil {
+		return
+	}
+	redactMessage(packed, redactors, resolver)
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(packed.Interface())
+	if err != nil {
+		return
+	}
+	m.Set(value, protoreflect.ValueOfBytes(b))
+}
+
+func shouldRedact(fd protoreflect.FieldDescriptor, redactors []FieldRedactor) bool {
+	for _, r := range redactors {
+		if r(fd) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactField replaces string values with a placeholder and clears all other values.
+func redactField(m protoreflect.Message, fd protoreflect.FieldDescriptor) {
+	redacted := protoreflect.ValueOfString(redactedFieldValue)
+	switch {
+	case fd.IsMap():
+		if fd.MapValue().Kind() != protoreflect.StringKind {
+			m.Clear(fd)
+			return
+		}
+		mp := m.Mutable(fd).Map()
+		var keys []protoreflect.MapKey
+		mp.Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+			keys = append(keys, k)
+			return true
+		})
+		for _, k := range keys {
+			mp.Set(k, redacted)
+		}
+	case fd.IsList():
+		if fd.Kind() != protoreflect.StringKind {
+			m.Clear(fd)
+			return
+		}
+		l := m.Mutable(fd).List()
+		for i := 0; i < l.Len(); i++ {
+			l.Set(i, redacted)
+		}
+	case fd.Kind() == protoreflect.StringKind:
+		m.Set(fd, redacted)
+	default:
+		m.Clear(fd)
+	}
+}
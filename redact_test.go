@@ -0,0 +1,116 @@
+package grpc_slog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// newSensitiveTypes builds a `(redact.sensitive)` field option and a `redact.LoginRequest` message whose password
+// field is annotated with it.
+func newSensitiveTypes(t *testing.T) (protoreflect.ExtensionType, protoreflect.MessageType) {
+	files := &protoregistry.Files{}
+	require.NoError(t, files.RegisterFile(descriptorpb.File_google_protobuf_descriptor_proto))
+	require.NoError(t, files.RegisterFile(anypb.File_google_protobuf_any_proto))
+
+	extFile, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("redact/sensitive.proto"),
+		Package:    proto.String("redact"),
+		Dependency: []string{"google/protobuf/descriptor.proto"},
+		Extension: []*descriptorpb.FieldDescriptorProto{{
+			Name:     proto.String("sensitive"),
+			Number:   proto.Int32(50000),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_BOOL.Enum(),
+			Extendee: proto.String(".google.protobuf.FieldOptions"),
+		}},
+	}, files)
+	require.NoError(t, err)
+	require.NoError(t, files.RegisterFile(extFile))
+	sensitive := dynamicpb.NewExtensionType(extFile.Extensions().ByName("sensitive"))
+
+	passwordOpts := &descriptorpb.FieldOptions{}
+	proto.SetExtension(passwordOpts, sensitive, true)
+	msgFile, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("redact/login.proto"),
+		Package:    proto.String("redact"),
+		Dependency: []string{"redact/sensitive.proto", "google/protobuf/any.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("LoginRequest"),
+			Field: []*descriptorpb.FieldDescriptorProto{{
+				Name:     proto.String("user"),
+				Number:   proto.Int32(1),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+				JsonName: proto.String("user"),
+			}, {
+				Name:     proto.String("password"),
+				Number:   proto.Int32(2),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+				JsonName: proto.String("password"),
+				Options:  passwordOpts,
+			}, {
+				Name:     proto.String("details"),
+				Number:   proto.Int32(3),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+				TypeName: proto.String(".google.protobuf.Any"),
+				JsonName: proto.String("details"),
+			}},
+		}},
+	}, files)
+	require.NoError(t, err)
+	return sensitive, dynamicpb.NewMessageType(msgFile.Messages().ByName("LoginRequest"))
+}
+
+func newLoginRequest(mt protoreflect.MessageType, user string, password string) protoreflect.Message {
+	m := mt.New()
+	fields := mt.Descriptor().Fields()
+	m.Set(fields.ByName("user"), protoreflect.ValueOfString(user))
+	m.Set(fields.ByName("password"), protoreflect.ValueOfString(password))
+	return m
+}
+
+func TestRedactProtoMessage_Extension(t *testing.T) {
+	sensitive, mt := newSensitiveTypes(t)
+	o := evaluateServerOpt([]Option{WithRedactionExtension(sensitive)})
+	fields := mt.Descriptor().Fields()
+
+	m := newLoginRequest(mt, "alice", "hunter2")
+	redacted := redactProtoMessage(m.Interface(), o.redactors, nil).ProtoReflect()
+
+	assert.Equal(t, "alice", redacted.Get(fields.ByName("user")).String(), "fields without the option must be kept")
+	assert.Equal(t, "[redacted]", redacted.Get(fields.ByName("password")).String(), "fields with the option must be redacted")
+	assert.Equal(t, "hunter2", m.Get(fields.ByName("password")).String(), "the original message must not be modified")
+}
+
+func TestRedactProtoMessage_Any(t *testing.T) {
+	sensitive, mt := newSensitiveTypes(t)
+	o := evaluateServerOpt([]Option{WithRedactionExtension(sensitive)})
+	fields := mt.Descriptor().Fields()
+	types := &protoregistry.Types{}
+	require.NoError(t, types.RegisterMessage(mt))
+
+	packed, err := anypb.New(newLoginRequest(mt, "bob", "swordfish").Interface())
+	require.NoError(t, err)
+	m := newLoginRequest(mt, "alice", "hunter2")
+	m.Set(fields.ByName("details"), protoreflect.ValueOfMessage(packed.ProtoReflect()))
+
+	redacted := redactProtoMessage(m.Interface(), o.redactors, types).ProtoReflect()
+
+	details := redacted.Get(fields.ByName("details")).Message()
+	value := details.Get(details.Descriptor().Fields().ByName("value")).Bytes()
+	inner := mt.New()
+	require.NoError(t, proto.Unmarshal(value, inner.Interface()))
+	assert.Equal(t, "bob", inner.Get(fields.ByName("user")).String(), "fields without the option must be kept")
+	assert.Equal(t, "[redacted]", inner.Get(fields.ByName("password")).String(), "fields inside Any values must be redacted")
+}
@@ -0,0 +1,89 @@
+package grpc_slog
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// DefaultRequestIDKey is the metadata key used for request IDs when WithRequestID is given an empty key.
+const DefaultRequestIDKey = "x-request-id"
+
+type requestIDMarker struct{}
+
+var requestIDMarkerKey = &requestIDMarker{}
+
+// WithRequestID enables request ID handling using the given metadata key, or DefaultRequestIDKey if it is empty.
+//
+// The server interceptors read the request ID from the incoming metadata, generating a new one if it is missing, add
+// it as a `request_id` field to the call logger and store it in the context of the call. The client interceptors
+// forward the request ID stored in the context into the outgoing metadata, unless it is already set, and log it.
+func WithRequestID(key string) Option {
+	return func(o *options) {
+		if key == "" {
+			key = DefaultRequestIDKey
+		}
+		o.requestIDKey = key
+	}
+}
+
+// WithRequestIDGenerator customizes the function generating request IDs for incoming calls without one.
+func WithRequestIDGenerator(f func() string) Option {
+	return func(o *options) {
+		o.requestIDFunc = f
+	}
+}
+
+// WithRequestIDHeader makes the server interceptors echo the request ID of the call in the response headers.
+func WithRequestIDHeader() Option {
+	return func(o *options) {
+		o.requestIDHeader = true
+	}
+}
+
+// DefaultRequestIDGenerator generates a random 128-bit hex encoded request ID.
+func DefaultRequestIDGenerator() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// ContextWithRequestID returns a context holding the request ID, which is forwarded by the client interceptors.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDMarkerKey, id)
+}
+
+// RequestIDFromContext returns the request ID held by the context.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDMarkerKey).(string)
+	return id, ok && id != ""
+}
+
+// incomingRequestID returns the request ID of an incoming call, generating one if the metadata does not contain it.
+func incomingRequestID(md metadata.MD, o *options) string {
+	if ids := md.Get(o.requestIDKey); len(ids) > 0 && ids[0] != "" {
+		return ids[0]
+	}
+	return o.requestIDFunc()
+}
+
+// outgoingRequestID adds the request ID held by ctx to its outgoing metadata, if it is not already set, and returns
+// the request ID of the outgoing call.
+func outgoingRequestID(ctx context.Context, o *options) (context.Context, string) {
+	if o.requestIDKey == "" {
+		return ctx, ""
+	}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if ids := md.Get(o.requestIDKey); len(ids) > 0 && ids[0] != "" {
+		return ctx, ids[0]
+	}
+	id, ok := RequestIDFromContext(ctx)
+	if !ok {
+		return ctx, ""
+	}
+	return metadata.AppendToOutgoingContext(ctx, o.requestIDKey, id), id
+}
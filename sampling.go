@@ -0,0 +1,113 @@
+package grpc_slog
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"cdr.dev/slog"
+	"google.golang.org/grpc/codes"
+)
+
+// Sampler decides whether the final line of a successful call is logged.
+type Sampler interface {
+	// Sample reports whether the call to fullMethod should be logged and the rate at which such calls are currently
+	// being sampled, between 0 and 1.
+	Sample(fullMethod string) (sampled bool, rate float64)
+}
+
+// SamplerFunc is an adapter to allow the use of ordinary functions as a Sampler.
+type SamplerFunc func(fullMethod string) (sampled bool, rate float64)
+
+// Sample calls f(fullMethod).
+func (f SamplerFunc) Sample(fullMethod string) (bool, float64) {
+	return f(fullMethod)
+}
+
+// WithSampling enables sampling of the final line of successful calls using the sampler. Calls that do not return
+// codes.OK, and calls that take longer than alwaysLogSlowerThan if it is positive, are always logged.
+// A `grpc.sampled_rate` field holding the rate at which the logged call was sampled is added to every final line.
+func WithSampling(s Sampler, alwaysLogSlowerThan time.Duration) Option {
+	return func(o *options) {
+		o.sampler = s
+		o.sampleSlowerThan = alwaysLogSlowerThan
+	}
+}
+
+// RateSampler returns a Sampler that randomly logs the given fraction of calls.
+func RateSampler(rate float64) Sampler {
+	return MethodRateSampler(nil, rate)
+}
+
+// MethodRateSampler returns a Sampler that randomly logs calls at the rate configured for their full method name,
+// falling back to defaultRate for methods that are not in rates.
+func MethodRateSampler(rates map[string]float64, defaultRate float64) Sampler {
+	return SamplerFunc(func(fullMethod string) (bool, float64) {
+		rate, ok := rates[fullMethod]
+		if !ok {
+			rate = defaultRate
+		}
+		return rand.Float64() < rate, rate
+	})
+}
+
+// BurstSampler returns a Sampler that logs the first calls to each method every second, and 1 in every thereafter
+// calls after that.
+func BurstSampler(first int, thereafter int) Sampler {
+	return &burstSampler{
+		first:      first,
+		thereafter: thereafter,
+		counters:   make(map[string]*burstCounter),
+	}
+}
+
+type burstSampler struct {
+	first      int
+	thereafter int
+
+	mu       sync.Mutex
+	counters map[string]*burstCounter
+}
+
+type burstCounter struct {
+	second int64
+	count  int
+}
+
+func (s *burstSampler) Sample(fullMethod string) (bool, float64) {
+	now := time.Now().Unix()
+
+	s.mu.Lock()
+	c, ok := s.counters[fullMethod]
+	if !ok {
+		c = &burstCounter{}
+		s.counters[fullMethod] = c
+	}
+	if c.second != now {
+		c.second = now
+		c.count = 0
+	}
+	c.count++
+	n := c.count
+	s.mu.Unlock()
+
+	if n <= s.first {
+		return true, 1
+	}
+	if s.thereafter <= 0 {
+		return false, 0
+	}
+	return (n-s.first)%s.thereafter == 0, 1 / float64(s.thereafter)
+}
+
+// sampleCall reports whether the final line of the call should be logged, and the sampling fields to log with it.
+func sampleCall(o *options, fullMethod string, code codes.Code, duration time.Duration) (bool, []slog.Field) {
+	if o.sampler == nil {
+		return true, nil
+	}
+	if code != codes.OK || o.isSlowCall(duration) || (o.sampleSlowerThan > 0 && duration > o.sampleSlowerThan) {
+		return true, []slog.Field{slog.F("grpc.sampled_rate", 1.0)}
+	}
+	sampled, rate := o.sampler.Sample(fullMethod)
+	return sampled, []slog.Field{slog.F("grpc.sampled_rate", rate)}
+}
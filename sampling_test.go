@@ -0,0 +1,42 @@
+package grpc_slog_test
+
+import (
+	"testing"
+
+	grpc_slog "github.com/hassieswift621/slog-grpc-mw"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBurstSampler(t *testing.T) {
+	s := grpc_slog.BurstSampler(2, 3)
+	var sampled int
+	for i := 0; i < 8; i++ {
+		ok, rate := s.Sample("/pkg.Service/Method")
+		if ok {
+			sampled++
+		}
+		if i < 2 {
+			assert.True(t, ok, "the first calls must be sampled")
+			assert.Equal(t, 1.0, rate, "the first calls must have a rate of 1")
+		} else {
+			assert.Equal(t, 1.0/3, rate, "later calls must have a rate of 1 in thereafter")
+		}
+	}
+	assert.Equal(t, 4, sampled, "the first 2 calls and 1 in 3 after must be sampled")
+
+	ok, rate := s.Sample("/pkg.Service/Other")
+	assert.True(t, ok, "methods must be sampled independently")
+	assert.Equal(t, 1.0, rate, "methods must be sampled independently")
+}
+
+func TestMethodRateSampler(t *testing.T) {
+	s := grpc_slog.MethodRateSampler(map[string]float64{"/pkg.Service/Never": 0}, 1)
+
+	ok, rate := s.Sample("/pkg.Service/Never")
+	assert.False(t, ok, "methods with a rate of 0 must never be sampled")
+	assert.Equal(t, 0.0, rate, "the method rate must be returned")
+
+	ok, rate = s.Sample("/pkg.Service/Always")
+	assert.True(t, ok, "methods with the default rate of 1 must always be sampled")
+	assert.Equal(t, 1.0, rate, "the default rate must be returned")
+}
@@ -2,6 +2,8 @@ package grpc_slog
 
 import (
 	"context"
+	"crypto/tls"
+	"fmt"
 	"path"
 	"time"
 
@@ -9,7 +11,11 @@ import (
 
 	"cdr.dev/slog"
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	grpc_ctxtags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 )
 
 var (
@@ -23,24 +29,26 @@ var (
 func UnaryServerInterceptor(logger slog.Logger, opts ...Option) grpc.UnaryServerInterceptor {
 	o := evaluateServerOpt(opts)
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		o := o.forMethod(info.FullMethod)
 		startTime := time.Now()
 
-		newCtx := newLoggerForCall(ctx, logger, info.FullMethod, startTime)
+		newCtx := newLoggerForCall(ctx, o, logger, info.FullMethod, startTime)
+		if id, ok := RequestIDFromContext(newCtx); ok && o.requestIDHeader {
+			_ = grpc.SetHeader(newCtx, metadata.Pairs(o.requestIDKey, id))
+		}
+		if o.logStart && o.shouldLog(info.FullMethod, nil) {
+			log(ctx, ctxslog.Extract(newCtx), o.startLevel, "started unary call")
+		}
 
 		resp, err := handler(newCtx, req)
 		if !o.shouldLog(info.FullMethod, err) {
 			return resp, err
 		}
-		code := o.codeFunc(err)
-		level := o.levelFunc(code)
-
-		// re-extract logger from newCtx, as it may have extra fields that changed in the holder.
-		extractedLogger := ctxslog.Extract(newCtx)
-		log(ctx, extractedLogger, level, "finished unary call with code "+code.String(),
-			slog.Error(err),
-			slog.F("grpc.code", code.String()),
-			o.durationFunc(time.Since(startTime)),
-		)
+		var fields []slog.Field
+		if o.messageStats {
+			fields = unaryStatsFields(req, resp, err)
+		}
+		logFinalServerLine(ctx, newCtx, o, info.FullMethod, startTime, err, "finished unary call with code ", fields...)
 
 		return resp, err
 	}
@@ -50,47 +58,145 @@ func UnaryServerInterceptor(logger slog.Logger, opts ...Option) grpc.UnaryServer
 func StreamServerInterceptor(logger slog.Logger, opts ...Option) grpc.StreamServerInterceptor {
 	o := evaluateServerOpt(opts)
 	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		o := o.forMethod(info.FullMethod)
 		startTime := time.Now()
-		newCtx := newLoggerForCall(stream.Context(), logger, info.FullMethod, startTime)
+		newCtx := newLoggerForCall(stream.Context(), o, logger, info.FullMethod, startTime)
+		if id, ok := RequestIDFromContext(newCtx); ok && o.requestIDHeader {
+			_ = stream.SetHeader(metadata.Pairs(o.requestIDKey, id))
+		}
+		if o.logStart && o.shouldLog(info.FullMethod, nil) {
+			log(stream.Context(), ctxslog.Extract(newCtx), o.startLevel, "started streaming call")
+		}
 		wrapped := grpc_middleware.WrapServerStream(stream)
 		wrapped.WrappedContext = newCtx
+		var handlerStream grpc.ServerStream = wrapped
+		stats := &streamStats{}
+		if o.messageStats {
+			handlerStream = &statsServerStream{ServerStream: wrapped, stats: stats}
+		}
 
-		err := handler(srv, wrapped)
+		err := handler(srv, handlerStream)
 		if !o.shouldLog(info.FullMethod, err) {
 			return err
 		}
-		code := o.codeFunc(err)
-		level := o.levelFunc(code)
-
-		// re-extract logger from newCtx, as it may have extra fields that changed in the holder.
-		extractedLogger := ctxslog.Extract(newCtx)
-		log(stream.Context(), extractedLogger, level, "finished streaming call with code "+code.String(),
-			slog.Error(err),
-			slog.F("grpc.code", code.String()),
-			o.durationFunc(time.Since(startTime)),
-		)
+		var fields []slog.Field
+		if o.messageStats {
+			fields = stats.fields()
+		}
+		logFinalServerLine(stream.Context(), newCtx, o, info.FullMethod, startTime, err, "finished streaming call with code ", fields...)
 
 		return err
 	}
 }
 
+// logFinalServerLine logs the completion of a call through the call-scoped logger held by loggerCtx.
+func logFinalServerLine(ctx context.Context, loggerCtx context.Context, o *options, fullMethodString string, startTime time.Time, err error, msg string, extraFields ...slog.Field) {
+	duration := time.Since(startTime)
+	code := o.codeFunc(err)
+	level, levelFields := o.callLevel(code, duration)
+	sampled, samplingFields := sampleCall(o, fullMethodString, code, duration)
+	if !sampled {
+		return
+	}
+
+	// re-extract logger from loggerCtx, as it may have extra fields that changed in the holder.
+	extractedLogger := ctxslog.Extract(loggerCtx)
+	fields := append([]slog.Field{
+		slog.Error(err),
+		slog.F("grpc.code", code.String()),
+		o.durationFunc(duration),
+	}, extraFields...)
+	fields = append(fields, levelFields...)
+	if o.statusDetails && err != nil {
+		fields = append(fields, statusDetailsFields(err)...)
+	}
+	log(ctx, extractedLogger, level, msg+code.String(), append(fields, samplingFields...)...)
+}
+
 func serverCallFields(fullMethodString string) []slog.Field {
 	service := path.Dir(fullMethodString)[1:]
 	method := path.Base(fullMethodString)
 	return []slog.Field{
 		SystemField,
 		ServerField,
-		{"grpc.service", service},
-		{"grpc.method", method},
+		{Name: "grpc.service", Value: service},
+		{Name: "grpc.method", Value: method},
 	}
 }
 
-func newLoggerForCall(ctx context.Context, logger slog.Logger, fullMethodString string, start time.Time) context.Context {
+func newLoggerForCall(ctx context.Context, o *options, logger slog.Logger, fullMethodString string, start time.Time) context.Context {
 	var f []slog.Field
 	f = append(f, slog.F("grpc.start_time", start.Format(time.RFC3339)))
 	if d, ok := ctx.Deadline(); ok {
 		f = append(f, slog.F("grpc.request.deadline", d.Format(time.RFC3339)))
 	}
-	callLog := logger.With(append(f, serverCallFields(fullMethodString)...)...)
-	return ctxslog.ToContext(ctx, callLog)
+	f = append(f, peerFields(ctx)...)
+	md, _ := metadata.FromIncomingContext(ctx)
+	f = append(f, metadataFields(md, &o.metadata)...)
+	if o.traceContext {
+		f = append(f, traceContextFields(md)...)
+	}
+	if o.requestIDKey != "" {
+		requestID := incomingRequestID(md, o)
+		f = append(f, slog.F("request_id", requestID))
+		ctx = ContextWithRequestID(ctx, requestID)
+	}
+	f = append(f, serverCallFields(fullMethodString)...)
+	callLog := logger
+	if o.debugHeaderKey != "" && hasMetadataValue(md, o.debugHeaderKey, o.debugHeaderValue) {
+		callLog = callLog.Leveled(slog.LevelDebug)
+	}
+	// The call fields are held by the context rather than the logger, so client interceptors can replace them.
+	ctx = ctxslog.ToContext(ctx, callLog)
+	ctxslog.AddFields(ctx, append(f, o.fields...)...)
+	return ctx
+}
+
+// peerFields returns the address of the calling peer and, for TLS connections, the negotiated TLS version and the
+// identity of the verified client certificate. The address is omitted when it is already set by grpc_ctxtags.
+func peerFields(ctx context.Context) []slog.Field {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	var f []slog.Field
+	if p.Addr != nil && !grpc_ctxtags.Extract(ctx).Has("peer.address") {
+		f = append(f, slog.F("peer.address", p.Addr.String()))
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return f
+	}
+	f = append(f, slog.F("peer.tls.version", tlsVersionName(tlsInfo.State.Version)))
+	if len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return f
+	}
+	cert := tlsInfo.State.VerifiedChains[0][0]
+	f = append(f, slog.F("peer.cert.subject", cert.Subject.String()))
+	if len(cert.DNSNames) > 0 {
+		f = append(f, slog.F("peer.cert.dns_names", cert.DNSNames))
+	}
+	if len(cert.URIs) > 0 {
+		uris := make([]string, 0, len(cert.URIs))
+		for _, u := range cert.URIs {
+			uris = append(uris, u.String())
+		}
+		f = append(f, slog.F("peer.cert.uris", uris))
+	}
+	return f
+}
+
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	default:
+		return fmt.Sprintf("0x%04X", version)
+	}
 }
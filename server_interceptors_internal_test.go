@@ -0,0 +1,71 @@
+package grpc_slog
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/url"
+	"testing"
+
+	"cdr.dev/slog"
+	grpc_ctxtags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+func fieldMap(fields []slog.Field) map[string]interface{} {
+	m := map[string]interface{}{}
+	for _, f := range fields {
+		m[f.Name] = f.Value
+	}
+	return m
+}
+
+func TestPeerFields_TLS(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://example.org/client")
+	cert := &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "client", Organization: []string{"Example"}},
+		DNSNames: []string{"client.example.org"},
+		URIs:     []*url.URL{spiffe},
+	}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234},
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+			Version:        tls.VersionTLS13,
+			VerifiedChains: [][]*x509.Certificate{{cert}},
+		}},
+	})
+
+	f := fieldMap(peerFields(ctx))
+
+	assert.Equal(t, "127.0.0.1:1234", f["peer.address"])
+	assert.Equal(t, "TLS 1.3", f["peer.tls.version"])
+	assert.Equal(t, "CN=client,O=Example", f["peer.cert.subject"])
+	assert.Equal(t, []string{"client.example.org"}, f["peer.cert.dns_names"])
+	assert.Equal(t, []string{"spiffe://example.org/client"}, f["peer.cert.uris"])
+}
+
+func TestPeerFields_WithoutVerifiedChain(t *testing.T) {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		Addr:     &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234},
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{Version: tls.VersionTLS12}},
+	})
+
+	f := fieldMap(peerFields(ctx))
+
+	assert.Equal(t, "TLS 1.2", f["peer.tls.version"])
+	assert.NotContains(t, f, "peer.cert.subject", "unverified peers must not have certificate fields")
+}
+
+func TestPeerFields_AddressFromTags(t *testing.T) {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234},
+	})
+	tags := grpc_ctxtags.NewTags().Set("peer.address", "127.0.0.1:1234")
+	ctx = grpc_ctxtags.SetInContext(ctx, tags)
+
+	assert.Empty(t, peerFields(ctx), "the address must not be duplicated when set by grpc_ctxtags")
+}
@@ -1,22 +1,30 @@
 package grpc_slog_test
 
 import (
+	"context"
 	"io"
+	"regexp"
 	"runtime"
 	"strings"
 	"testing"
 	"time"
 
 	"cdr.dev/slog"
+	"github.com/golang/protobuf/proto"
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpc_ctxtags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	grpc_testing "github.com/grpc-ecosystem/go-grpc-middleware/testing"
 	pb_testproto "github.com/grpc-ecosystem/go-grpc-middleware/testing/testproto"
 	grpc_slog "github.com/hassieswift621/slog-grpc-mw"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 func customCodeToLevel(c codes.Code) slog.Level {
@@ -72,6 +80,7 @@ func (s *slogServerSuite) TestPing_WithCustomTags() {
 		assert.Equal(s.T(), f["custom_tags.string"], "something", "all lines must contain `custom_tags.string`")
 		assert.Equal(s.T(), f["grpc.request.value"], "something", "all lines must contain fields extracted")
 		assert.Equal(s.T(), f["custom_field"], "custom_value", "all lines must contain `custom_field`")
+		assert.Contains(s.T(), f, "peer.address", "all lines must contain the address of the peer")
 
 		assert.Contains(s.T(), f, "custom_tags.int", "all lines must contain `custom_tags.int`")
 		require.Contains(s.T(), f, "grpc.start_time", "all lines must contain the start time")
@@ -351,3 +360,554 @@ func (s *slogServerOverriddenDeciderSuite) TestPingList_HasOverriddenDecider() {
 	assert.NotContains(s.T(), f, "grpc.time_ms", "handler's message must not contain default duration")
 	assert.NotContains(s.T(), f, "grpc.duration", "handler's message must not contain overridden duration")
 }
+
+func TestSlogServerStartLogSuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skip("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+	opts := []grpc_slog.Option{
+		grpc_slog.WithStartLog(slog.LevelDebug),
+	}
+	b := newBaseSlogSuite(t)
+	b.log = b.log.Leveled(slog.LevelDebug)
+	b.InterceptorTestSuite.ServerOpts = []grpc.ServerOption{
+		grpc_middleware.WithStreamServerChain(
+			grpc_ctxtags.StreamServerInterceptor(),
+			grpc_slog.StreamServerInterceptor(b.log, opts...)),
+		grpc_middleware.WithUnaryServerChain(
+			grpc_ctxtags.UnaryServerInterceptor(),
+			grpc_slog.UnaryServerInterceptor(b.log, opts...)),
+	}
+	suite.Run(t, &slogServerStartLogSuite{b})
+}
+
+type slogServerStartLogSuite struct {
+	*slogBaseSuite
+}
+
+func (s *slogServerStartLogSuite) TestPing_HasStartLine() {
+	deadline := time.Now().Add(3 * time.Second)
+	_, err := s.Client.Ping(s.DeadlineCtx(deadline), goodPing)
+	require.NoError(s.T(), err, "there must be not be an error on a successful call")
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 3, "three log statements should be logged")
+
+	// Get slog fields.
+	f := msgs[0]["fields"].(map[string]interface{})
+
+	assert.Equal(s.T(), msgs[0]["msg"], "started unary call", "first line must be the start line")
+	assert.Equal(s.T(), msgs[0]["level"], "DEBUG", "start line must be logged at the configured level")
+	assert.Equal(s.T(), f["grpc.service"], "mwitkow.testproto.TestService", "start line must contain service name")
+	assert.Equal(s.T(), f["grpc.method"], "Ping", "start line must contain method name")
+	assert.Equal(s.T(), f["grpc.request.deadline"], deadline.Format(time.RFC3339), "start line must contain the deadline")
+	assert.NotContains(s.T(), f, "grpc.code", "start line must not contain a code")
+	assert.Equal(s.T(), msgs[2]["msg"], "finished unary call with code OK", "last line must be the final line")
+}
+
+func (s *slogServerStartLogSuite) TestPingList_HasStartLine() {
+	stream, err := s.Client.PingList(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "should not fail on establishing the stream")
+	for {
+		_, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(s.T(), err, "reading stream should not fail")
+	}
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 3, "three log statements should be logged")
+
+	// Get slog fields.
+	f := msgs[0]["fields"].(map[string]interface{})
+
+	assert.Equal(s.T(), msgs[0]["msg"], "started streaming call", "first line must be the start line")
+	assert.Equal(s.T(), f["grpc.method"], "PingList", "start line must contain method name")
+	assert.Equal(s.T(), msgs[2]["msg"], "finished streaming call with code OK", "last line must be the final line")
+}
+
+func TestSlogServerMetadataSuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skip("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+	opts := []grpc_slog.Option{
+		grpc_slog.WithMetadataAllowlist("x-request-id", "authorization", "x-trace-bin"),
+	}
+	b := newBaseSlogSuite(t)
+	b.InterceptorTestSuite.ServerOpts = []grpc.ServerOption{
+		grpc_middleware.WithStreamServerChain(
+			grpc_ctxtags.StreamServerInterceptor(),
+			grpc_slog.StreamServerInterceptor(b.log, opts...)),
+		grpc_middleware.WithUnaryServerChain(
+			grpc_ctxtags.UnaryServerInterceptor(),
+			grpc_slog.UnaryServerInterceptor(b.log, opts...)),
+	}
+	suite.Run(t, &slogServerMetadataSuite{b})
+}
+
+type slogServerMetadataSuite struct {
+	*slogBaseSuite
+}
+
+func (s *slogServerMetadataSuite) TestPing_HasAllowedMetadata() {
+	ctx := metadata.AppendToOutgoingContext(s.SimpleCtx(),
+		"x-request-id", "abc",
+		"authorization", "Bearer secret",
+		"x-trace-bin", "binary",
+		"x-other", "other",
+	)
+	_, err := s.Client.Ping(ctx, goodPing)
+	require.NoError(s.T(), err, "there must be not be an error on a successful call")
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 2, "two log statements should be logged")
+
+	for _, m := range msgs {
+		// Get slog fields.
+		f := m["fields"].(map[string]interface{})
+
+		assert.Equal(s.T(), f["grpc.request.metadata.x-request-id"], "abc", "all lines must contain allowed metadata")
+		assert.Equal(s.T(), f["grpc.request.metadata.authorization"], "[redacted]", "authorization must be redacted")
+		assert.Equal(s.T(), f["grpc.request.metadata.x-trace-bin"], "[redacted]", "binary metadata must be redacted")
+		assert.NotContains(s.T(), f, "grpc.request.metadata.x-other", "metadata not in the allowlist must not be logged")
+		assert.NotContains(s.T(), f, "grpc.request.metadata.user-agent", "metadata not in the allowlist must not be logged")
+	}
+}
+
+func TestSlogServerMessageStatsSuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skip("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+	opts := []grpc_slog.Option{
+		grpc_slog.WithMessageStats(),
+	}
+	b := newBaseSlogSuite(t)
+	b.InterceptorTestSuite.ServerOpts = []grpc.ServerOption{
+		grpc_middleware.WithStreamServerChain(
+			grpc_ctxtags.StreamServerInterceptor(),
+			grpc_slog.StreamServerInterceptor(b.log, opts...)),
+		grpc_middleware.WithUnaryServerChain(
+			grpc_ctxtags.UnaryServerInterceptor(),
+			grpc_slog.UnaryServerInterceptor(b.log, opts...)),
+	}
+	suite.Run(t, &slogServerMessageStatsSuite{b})
+}
+
+type slogServerMessageStatsSuite struct {
+	*slogBaseSuite
+}
+
+func (s *slogServerMessageStatsSuite) TestPing_HasMessageSizes() {
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "there must be not be an error on a successful call")
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 2, "two log statements should be logged")
+
+	// Get slog fields.
+	f := msgs[1]["fields"].(map[string]interface{})
+
+	assert.EqualValues(s.T(), proto.Size(goodPing), f["grpc.request.size_bytes"], "final line must contain the request size")
+	assert.Contains(s.T(), f, "grpc.response.size_bytes", "final line must contain the response size")
+}
+
+func (s *slogServerMessageStatsSuite) TestPingList_HasMessageCounts() {
+	stream, err := s.Client.PingList(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "should not fail on establishing the stream")
+	for {
+		_, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(s.T(), err, "reading stream should not fail")
+	}
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 2, "two log statements should be logged")
+
+	// Get slog fields.
+	f := msgs[1]["fields"].(map[string]interface{})
+
+	assert.EqualValues(s.T(), grpc_testing.ListResponseCount, f["grpc.stream.msgs_sent"], "final line must contain the number of messages sent")
+	assert.EqualValues(s.T(), 1, f["grpc.stream.msgs_received"], "final line must contain the number of messages received")
+	assert.EqualValues(s.T(), proto.Size(goodPing), f["grpc.stream.bytes_received"], "final line must contain the bytes received")
+	assert.Greater(s.T(), f["grpc.stream.bytes_sent"], float64(0), "final line must contain the bytes sent")
+}
+
+func TestSlogServerSamplingSuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skip("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+	opts := []grpc_slog.Option{
+		grpc_slog.WithSampling(grpc_slog.RateSampler(0), 0),
+	}
+	b := newBaseSlogSuite(t)
+	b.InterceptorTestSuite.ServerOpts = []grpc.ServerOption{
+		grpc_middleware.WithStreamServerChain(
+			grpc_ctxtags.StreamServerInterceptor(),
+			grpc_slog.StreamServerInterceptor(b.log, opts...)),
+		grpc_middleware.WithUnaryServerChain(
+			grpc_ctxtags.UnaryServerInterceptor(),
+			grpc_slog.UnaryServerInterceptor(b.log, opts...)),
+	}
+	suite.Run(t, &slogServerSamplingSuite{b})
+}
+
+type slogServerSamplingSuite struct {
+	*slogBaseSuite
+}
+
+func (s *slogServerSamplingSuite) TestPing_IsSampledOut() {
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "there must be not be an error on a successful call")
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 1, "only the handler log statement should be logged")
+	assert.Equal(s.T(), msgs[0]["msg"], "some ping", "handler's message must contain user message")
+}
+
+func (s *slogServerSamplingSuite) TestPingError_IsAlwaysLogged() {
+	_, err := s.Client.PingError(
+		s.SimpleCtx(),
+		&pb_testproto.PingRequest{Value: "something", ErrorCodeReturned: uint32(codes.NotFound)})
+	require.Error(s.T(), err, "each call here must return an error")
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 1, "only the interceptor log message is printed in PingErr")
+
+	// Get slog fields.
+	f := msgs[0]["fields"].(map[string]interface{})
+
+	assert.Equal(s.T(), f["grpc.code"], codes.NotFound.String(), "final line must contain the code")
+	assert.EqualValues(s.T(), 1, f["grpc.sampled_rate"], "calls that are always logged must have a rate of 1")
+}
+
+func TestSlogServerSlowCallSuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skip("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+	opts := []grpc_slog.Option{
+		grpc_slog.WithSlowCallThreshold(time.Nanosecond, slog.LevelWarn),
+		grpc_slog.WithSampling(grpc_slog.RateSampler(0), 0),
+	}
+	b := newBaseSlogSuite(t)
+	b.InterceptorTestSuite.ServerOpts = []grpc.ServerOption{
+		grpc_middleware.WithStreamServerChain(
+			grpc_ctxtags.StreamServerInterceptor(),
+			grpc_slog.StreamServerInterceptor(b.log, opts...)),
+		grpc_middleware.WithUnaryServerChain(
+			grpc_ctxtags.UnaryServerInterceptor(),
+			grpc_slog.UnaryServerInterceptor(b.log, opts...)),
+	}
+	suite.Run(t, &slogServerSlowCallSuite{b})
+}
+
+type slogServerSlowCallSuite struct {
+	*slogBaseSuite
+}
+
+func (s *slogServerSlowCallSuite) TestPing_IsEscalated() {
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "there must be not be an error on a successful call")
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 2, "slow calls must not be sampled out")
+
+	// Get slog fields.
+	f := msgs[1]["fields"].(map[string]interface{})
+
+	assert.Equal(s.T(), msgs[1]["msg"], "finished unary call with code OK", "handler's message must contain user message")
+	assert.Equal(s.T(), msgs[1]["level"], "WARN", "slow calls must be escalated to the configured level")
+	assert.Equal(s.T(), f["grpc.slow"], true, "slow calls must be marked")
+}
+
+func (s *slogServerSlowCallSuite) TestPingError_KeepsHigherLevel() {
+	_, err := s.Client.PingError(
+		s.SimpleCtx(),
+		&pb_testproto.PingRequest{Value: "something", ErrorCodeReturned: uint32(codes.Internal)})
+	require.Error(s.T(), err, "each call here must return an error")
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 1, "only the interceptor log message is printed in PingErr")
+	assert.Equal(s.T(), msgs[0]["level"], "ERROR", "slow calls must not be lowered to the configured level")
+}
+
+func TestSlogServerDebugHeaderSuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skip("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+	opts := []grpc_slog.Option{
+		grpc_slog.WithStartLog(slog.LevelDebug),
+		grpc_slog.WithDebugHeader("x-debug-log", "1"),
+	}
+	b := newBaseSlogSuite(t)
+	b.InterceptorTestSuite.ServerOpts = []grpc.ServerOption{
+		grpc_middleware.WithStreamServerChain(
+			grpc_ctxtags.StreamServerInterceptor(),
+			grpc_slog.StreamServerInterceptor(b.log, opts...)),
+		grpc_middleware.WithUnaryServerChain(
+			grpc_ctxtags.UnaryServerInterceptor(),
+			grpc_slog.UnaryServerInterceptor(b.log, opts...)),
+	}
+	suite.Run(t, &slogServerDebugHeaderSuite{b})
+}
+
+type slogServerDebugHeaderSuite struct {
+	*slogBaseSuite
+}
+
+func (s *slogServerDebugHeaderSuite) TestPing_WithoutHeader() {
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "there must be not be an error on a successful call")
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 2, "debug lines must not be logged without the header")
+}
+
+func (s *slogServerDebugHeaderSuite) TestPing_WithHeader() {
+	ctx := metadata.AppendToOutgoingContext(s.SimpleCtx(), "x-debug-log", "1")
+	_, err := s.Client.Ping(ctx, goodPing)
+	require.NoError(s.T(), err, "there must be not be an error on a successful call")
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 3, "debug lines must be logged with the header")
+	assert.Equal(s.T(), msgs[0]["msg"], "started unary call", "the debug start line must be logged")
+	assert.Equal(s.T(), msgs[0]["level"], "DEBUG", "the start line must be logged at debug level")
+}
+
+func TestSlogServerTraceContextSuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skip("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+	opts := []grpc_slog.Option{
+		grpc_slog.WithTraceContext(),
+	}
+	b := newBaseSlogSuite(t)
+	b.InterceptorTestSuite.ServerOpts = []grpc.ServerOption{
+		grpc_middleware.WithStreamServerChain(
+			grpc_ctxtags.StreamServerInterceptor(),
+			grpc_slog.StreamServerInterceptor(b.log, opts...)),
+		grpc_middleware.WithUnaryServerChain(
+			grpc_ctxtags.UnaryServerInterceptor(),
+			grpc_slog.UnaryServerInterceptor(b.log, opts...)),
+	}
+	suite.Run(t, &slogServerTraceContextSuite{b})
+}
+
+type slogServerTraceContextSuite struct {
+	*slogBaseSuite
+}
+
+func (s *slogServerTraceContextSuite) TestPing_HasTraceContext() {
+	ctx := metadata.AppendToOutgoingContext(s.SimpleCtx(), "traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	_, err := s.Client.Ping(ctx, goodPing)
+	require.NoError(s.T(), err, "there must be not be an error on a successful call")
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 2, "two log statements should be logged")
+
+	for _, m := range msgs {
+		// Get slog fields.
+		f := m["fields"].(map[string]interface{})
+
+		assert.Equal(s.T(), f["trace_id"], "4bf92f3577b34da6a3ce929d0e0e4736", "all lines must contain the trace ID")
+		assert.Equal(s.T(), f["span_id"], "00f067aa0ba902b7", "all lines must contain the span ID")
+		assert.Equal(s.T(), f["trace_flags"], "01", "all lines must contain the trace flags")
+	}
+}
+
+func (s *slogServerTraceContextSuite) TestPing_WithInvalidTraceContext() {
+	ctx := metadata.AppendToOutgoingContext(s.SimpleCtx(), "traceparent", "00-00000000000000000000000000000000-00f067aa0ba902b7-01")
+	_, err := s.Client.Ping(ctx, goodPing)
+	require.NoError(s.T(), err, "there must be not be an error on a successful call")
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 2, "two log statements should be logged")
+
+	for _, m := range msgs {
+		assert.NotContains(s.T(), m["fields"], "trace_id", "invalid trace contexts must be ignored")
+	}
+}
+
+func TestSlogServerRequestIDSuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skip("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+	opts := []grpc_slog.Option{
+		grpc_slog.WithRequestID(""),
+		grpc_slog.WithRequestIDGenerator(func() string { return "generated" }),
+		grpc_slog.WithRequestIDHeader(),
+	}
+	b := newBaseSlogSuite(t)
+	b.InterceptorTestSuite.ServerOpts = []grpc.ServerOption{
+		grpc_middleware.WithStreamServerChain(
+			grpc_ctxtags.StreamServerInterceptor(),
+			grpc_slog.StreamServerInterceptor(b.log, opts...)),
+		grpc_middleware.WithUnaryServerChain(
+			grpc_ctxtags.UnaryServerInterceptor(),
+			grpc_slog.UnaryServerInterceptor(b.log, opts...)),
+	}
+	suite.Run(t, &slogServerRequestIDSuite{b})
+}
+
+type slogServerRequestIDSuite struct {
+	*slogBaseSuite
+}
+
+func (s *slogServerRequestIDSuite) TestPing_UsesIncomingRequestID() {
+	var header metadata.MD
+	ctx := metadata.AppendToOutgoingContext(s.SimpleCtx(), "x-request-id", "abc")
+	_, err := s.Client.Ping(ctx, goodPing, grpc.Header(&header))
+	require.NoError(s.T(), err, "there must be not be an error on a successful call")
+	assert.Equal(s.T(), []string{"abc"}, header.Get("x-request-id"), "the request ID must be echoed in the response headers")
+
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 2, "two log statements should be logged")
+	for _, m := range msgs {
+		// Get slog fields.
+		f := m["fields"].(map[string]interface{})
+
+		assert.Equal(s.T(), f["request_id"], "abc", "all lines must contain the incoming request ID")
+	}
+}
+
+func (s *slogServerRequestIDSuite) TestPing_GeneratesRequestID() {
+	var header metadata.MD
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing, grpc.Header(&header))
+	require.NoError(s.T(), err, "there must be not be an error on a successful call")
+	assert.Equal(s.T(), []string{"generated"}, header.Get("x-request-id"), "the generated request ID must be echoed in the response headers")
+
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 2, "two log statements should be logged")
+	for _, m := range msgs {
+		// Get slog fields.
+		f := m["fields"].(map[string]interface{})
+
+		assert.Equal(s.T(), f["request_id"], "generated", "all lines must contain the generated request ID")
+	}
+}
+
+type detailedErrorPingService struct {
+	pb_testproto.TestServiceServer
+}
+
+func (s *detailedErrorPingService) PingError(ctx context.Context, ping *pb_testproto.PingRequest) (*pb_testproto.Empty, error) {
+	st, err := status.New(codes.InvalidArgument, "invalid ping").WithDetails(
+		&errdetails.ErrorInfo{Reason: "INVALID_PING", Domain: "testproto.mwitkow"},
+		&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: "value", Description: "must not be empty"},
+		}},
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(2 * time.Second)},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return nil, st.Err()
+}
+
+func TestSlogServerStatusDetailsSuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skip("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+	opts := []grpc_slog.Option{
+		grpc_slog.WithStatusDetails(),
+	}
+	b := newBaseSlogSuite(t)
+	b.InterceptorTestSuite.TestService = &detailedErrorPingService{&grpc_testing.TestPingService{T: t}}
+	b.InterceptorTestSuite.ServerOpts = []grpc.ServerOption{
+		grpc_middleware.WithUnaryServerChain(
+			grpc_ctxtags.UnaryServerInterceptor(),
+			grpc_slog.UnaryServerInterceptor(b.log, opts...)),
+	}
+	suite.Run(t, &slogServerStatusDetailsSuite{b})
+}
+
+type slogServerStatusDetailsSuite struct {
+	*slogBaseSuite
+}
+
+func (s *slogServerStatusDetailsSuite) TestPingError_HasStatusDetails() {
+	_, err := s.Client.PingError(s.SimpleCtx(), goodPing)
+	require.Error(s.T(), err, "each call here must return an error")
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 1, "only the interceptor log message is printed in PingErr")
+
+	// Get slog fields.
+	f := msgs[0]["fields"].(map[string]interface{})
+
+	assert.Equal(s.T(), f["grpc.error.reason"], "INVALID_PING", "the error reason must be logged")
+	assert.Equal(s.T(), f["grpc.error.domain"], "testproto.mwitkow", "the error domain must be logged")
+	assert.Equal(s.T(), f["grpc.error.retry_delay"], "2s", "the retry delay must be logged")
+	assert.Equal(s.T(), f["grpc.error.field_violations"], []interface{}{
+		map[string]interface{}{"field": "value", "description": "must not be empty"},
+	}, "the field violations must be logged")
+}
+
+func TestSlogServerMethodOptionsSuite(t *testing.T) {
+	if strings.HasPrefix(runtime.Version(), "go1.7") {
+		t.Skip("Skipping due to json.RawMessage incompatibility with go1.7")
+		return
+	}
+	opts := []grpc_slog.Option{
+		grpc_slog.WithFields(slog.F("team", "core")),
+		grpc_slog.WithMethodRegexpOptions(regexp.MustCompile(`Ping`),
+			grpc_slog.WithFields(slog.F("matched", "regexp"))),
+		grpc_slog.WithMethodOptions("/mwitkow.testproto.TestService/Ping",
+			grpc_slog.WithFields(slog.F("matched", "exact")),
+			grpc_slog.WithSlowCallThreshold(time.Nanosecond, slog.LevelWarn)),
+		grpc_slog.WithMethodOptions("/mwitkow.testproto.TestService/*",
+			grpc_slog.WithFields(slog.F("matched", "service")),
+			grpc_slog.WithLevels(func(codes.Code) slog.Level { return slog.LevelWarn })),
+		grpc_slog.WithMethodOptions("/mwitkow.testproto.TestService/PingEmpty",
+			grpc_slog.WithSampling(grpc_slog.RateSampler(0), 0)),
+	}
+	b := newBaseSlogSuite(t)
+	b.InterceptorTestSuite.ServerOpts = []grpc.ServerOption{
+		grpc_middleware.WithStreamServerChain(
+			grpc_ctxtags.StreamServerInterceptor(),
+			grpc_slog.StreamServerInterceptor(b.log, opts...)),
+		grpc_middleware.WithUnaryServerChain(
+			grpc_ctxtags.UnaryServerInterceptor(),
+			grpc_slog.UnaryServerInterceptor(b.log, opts...)),
+	}
+	suite.Run(t, &slogServerMethodOptionsSuite{b})
+}
+
+type slogServerMethodOptionsSuite struct {
+	*slogBaseSuite
+}
+
+func (s *slogServerMethodOptionsSuite) TestPing_UsesExactMethodOptions() {
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "there must be not be an error on a successful call")
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 2, "two log statements should be logged")
+
+	for _, m := range msgs {
+		f := m["fields"].(map[string]interface{})
+		assert.Equal(s.T(), f["team"], "core", "all lines must contain the fields of the interceptor")
+		assert.Equal(s.T(), f["matched"], "exact", "exact method options must take precedence")
+	}
+
+	f := msgs[1]["fields"].(map[string]interface{})
+	assert.Equal(s.T(), msgs[1]["level"], "WARN", "the slow call threshold of the method must be used")
+	assert.Equal(s.T(), f["grpc.slow"], true, "slow calls must be marked")
+}
+
+func (s *slogServerMethodOptionsSuite) TestPingError_UsesServiceOptions() {
+	_, err := s.Client.PingError(
+		s.SimpleCtx(),
+		&pb_testproto.PingRequest{Value: "something", ErrorCodeReturned: uint32(codes.Internal)})
+	require.Error(s.T(), err, "each call here must return an error")
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 1, "only the interceptor log message is printed in PingErr")
+
+	f := msgs[0]["fields"].(map[string]interface{})
+	assert.Equal(s.T(), msgs[0]["level"], "WARN", "the levels of the service must be used")
+	assert.Equal(s.T(), f["matched"], "service", "service options must take precedence over regexp options")
+	assert.NotContains(s.T(), f, "grpc.slow", "options of other methods must not be used")
+}
+
+func (s *slogServerMethodOptionsSuite) TestPingEmpty_IsSampledOut() {
+	_, err := s.Client.PingEmpty(s.SimpleCtx(), &pb_testproto.Empty{})
+	require.NoError(s.T(), err, "there must be not be an error on a successful call")
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 0, "the sampler of the method must be used")
+}
@@ -0,0 +1,100 @@
+package grpc_slog
+
+import (
+	"sync/atomic"
+
+	"cdr.dev/slog"
+	protov1 "github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// WithMessageStats enables logging of message sizes on the final line of a call. Unary calls log
+// `grpc.request.size_bytes` and `grpc.response.size_bytes`, streaming calls log `grpc.stream.msgs_sent`,
+// `grpc.stream.msgs_received`, `grpc.stream.bytes_sent` and `grpc.stream.bytes_received`.
+func WithMessageStats() Option {
+	return func(o *options) {
+		o.messageStats = true
+	}
+}
+
+// messageSize returns the serialized size of the protobuf message, or -1 if m is not a protobuf message.
+func messageSize(m interface{}) int {
+	switch p := m.(type) {
+	case proto.Message:
+		return proto.Size(p)
+	case protov1.Message:
+		return protov1.Size(p)
+	default:
+		return -1
+	}
+}
+
+// unaryStatsFields returns the size fields of a unary call. The response size is only logged when there is a response.
+func unaryStatsFields(req, resp interface{}, err error) []slog.Field {
+	var f []slog.Field
+	if n := messageSize(req); n >= 0 {
+		f = append(f, slog.F("grpc.request.size_bytes", n))
+	}
+	if err != nil {
+		return f
+	}
+	if n := messageSize(resp); n >= 0 {
+		f = append(f, slog.F("grpc.response.size_bytes", n))
+	}
+	return f
+}
+
+// streamStats counts the messages and bytes sent and received on a stream. It is safe for concurrent use, as sending
+// and receiving may happen on different goroutines.
+type streamStats struct {
+	msgsSent      int64
+	msgsReceived  int64
+	bytesSent     int64
+	bytesReceived int64
+}
+
+func (s *streamStats) sent(m interface{}) {
+	atomic.AddInt64(&s.msgsSent, 1)
+	if n := messageSize(m); n > 0 {
+		atomic.AddInt64(&s.bytesSent, int64(n))
+	}
+}
+
+func (s *streamStats) received(m interface{}) {
+	atomic.AddInt64(&s.msgsReceived, 1)
+	if n := messageSize(m); n > 0 {
+		atomic.AddInt64(&s.bytesReceived, int64(n))
+	}
+}
+
+func (s *streamStats) fields() []slog.Field {
+	return []slog.Field{
+		slog.F("grpc.stream.msgs_sent", atomic.LoadInt64(&s.msgsSent)),
+		slog.F("grpc.stream.msgs_received", atomic.LoadInt64(&s.msgsReceived)),
+		slog.F("grpc.stream.bytes_sent", atomic.LoadInt64(&s.bytesSent)),
+		slog.F("grpc.stream.bytes_received", atomic.LoadInt64(&s.bytesReceived)),
+	}
+}
+
+// statsServerStream wraps a grpc.ServerStream and records the messages sent and received.
+type statsServerStream struct {
+	grpc.ServerStream
+	stats *streamStats
+}
+
+func (s *statsServerStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.stats.sent(m)
+	}
+	return err
+}
+
+func (s *statsServerStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.stats.received(m)
+	}
+	return err
+}
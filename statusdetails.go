@@ -0,0 +1,76 @@
+package grpc_slog
+
+import (
+	"cdr.dev/slog"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+)
+
+// WithStatusDetails enables logging of the typed details of error statuses as structured fields on the final line of
+// a call. The supported details are ErrorInfo (`grpc.error.reason`, `grpc.error.domain`, `grpc.error.metadata`),
+// BadRequest (`grpc.error.field_violations`), RetryInfo (`grpc.error.retry_delay`), DebugInfo (`grpc.error.debug_detail`,
+// `grpc.error.stack_entries`), QuotaFailure (`grpc.error.quota_violations`), PreconditionFailure
+// (`grpc.error.precondition_violations`) and ResourceInfo (`grpc.error.resource_type`, `grpc.error.resource_name`).
+func WithStatusDetails() Option {
+	return func(o *options) {
+		o.statusDetails = true
+	}
+}
+
+// statusDetailsFields returns the fields of the supported details of the status of err.
+func statusDetailsFields(err error) []slog.Field {
+	s, ok := status.FromError(err)
+	if !ok || s == nil {
+		return nil
+	}
+	var f []slog.Field
+	for _, d := range s.Details() {
+		switch d := d.(type) {
+		case *errdetails.ErrorInfo:
+			f = append(f, slog.F("grpc.error.reason", d.GetReason()), slog.F("grpc.error.domain", d.GetDomain()))
+			if len(d.GetMetadata()) > 0 {
+				f = append(f, slog.F("grpc.error.metadata", d.GetMetadata()))
+			}
+		case *errdetails.BadRequest:
+			violations := make([]slog.Map, 0, len(d.GetFieldViolations()))
+			for _, v := range d.GetFieldViolations() {
+				violations = append(violations, slog.M(
+					slog.F("field", v.GetField()),
+					slog.F("description", v.GetDescription()),
+				))
+			}
+			f = append(f, slog.F("grpc.error.field_violations", violations))
+		case *errdetails.RetryInfo:
+			if d.GetRetryDelay() != nil {
+				f = append(f, slog.F("grpc.error.retry_delay", d.GetRetryDelay().AsDuration()))
+			}
+		case *errdetails.DebugInfo:
+			f = append(f, slog.F("grpc.error.debug_detail", d.GetDetail()))
+			if len(d.GetStackEntries()) > 0 {
+				f = append(f, slog.F("grpc.error.stack_entries", d.GetStackEntries()))
+			}
+		case *errdetails.QuotaFailure:
+			violations := make([]slog.Map, 0, len(d.GetViolations()))
+			for _, v := range d.GetViolations() {
+				violations = append(violations, slog.M(
+					slog.F("subject", v.GetSubject()),
+					slog.F("description", v.GetDescription()),
+				))
+			}
+			f = append(f, slog.F("grpc.error.quota_violations", violations))
+		case *errdetails.PreconditionFailure:
+			violations := make([]slog.Map, 0, len(d.GetViolations()))
+			for _, v := range d.GetViolations() {
+				violations = append(violations, slog.M(
+					slog.F("type", v.GetType()),
+					slog.F("subject", v.GetSubject()),
+					slog.F("description", v.GetDescription()),
+				))
+			}
+			f = append(f, slog.F("grpc.error.precondition_violations", violations))
+		case *errdetails.ResourceInfo:
+			f = append(f, slog.F("grpc.error.resource_type", d.GetResourceType()), slog.F("grpc.error.resource_name", d.GetResourceName()))
+		}
+	}
+	return f
+}
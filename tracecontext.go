@@ -0,0 +1,70 @@
+package grpc_slog
+
+import (
+	"strings"
+
+	"cdr.dev/slog"
+	"google.golang.org/grpc/metadata"
+)
+
+// traceparentKey is the metadata key of the W3C trace context, see https://www.w3.org/TR/trace-context/.
+const traceparentKey = "traceparent"
+
+// WithTraceContext adds `trace_id`, `span_id` and `trace_flags` fields from the W3C `traceparent` metadata to the
+// call logger. The server interceptors read the incoming metadata, and so also add the fields to all loggers extracted
+// with `ctxslog.Extract`, while the client interceptors read the outgoing metadata.
+func WithTraceContext() Option {
+	return func(o *options) {
+		o.traceContext = true
+	}
+}
+
+// traceContextFields returns the fields of the traceparent in the metadata, or nil if it is missing or invalid.
+func traceContextFields(md metadata.MD) []slog.Field {
+	values := md.Get(traceparentKey)
+	if len(values) == 0 {
+		return nil
+	}
+	traceID, spanID, flags, ok := parseTraceparent(values[0])
+	if !ok {
+		return nil
+	}
+	return []slog.Field{
+		slog.F("trace_id", traceID),
+		slog.F("span_id", spanID),
+		slog.F("trace_flags", flags),
+	}
+}
+
+// parseTraceparent parses a `version-traceid-parentid-flags` traceparent header value.
+func parseTraceparent(v string) (traceID string, spanID string, flags string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) < 4 {
+		return "", "", "", false
+	}
+	version := parts[0]
+	if !isLowerHex(version, 2) || version == "ff" || (version == "00" && len(parts) != 4) {
+		return "", "", "", false
+	}
+	traceID, spanID, flags = parts[1], parts[2], parts[3]
+	if !isLowerHex(traceID, 32) || isZeros(traceID) || !isLowerHex(spanID, 16) || isZeros(spanID) || !isLowerHex(flags, 2) {
+		return "", "", "", false
+	}
+	return traceID, spanID, flags, true
+}
+
+func isLowerHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func isZeros(s string) bool {
+	return strings.Trim(s, "0") == ""
+}